@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+  admin api:
+  GET    /_cache_admin/                          列出所有 group 以及占用的内存
+  GET    /_cache_admin/{groupName}               查看 group
+  PUT    /_cache_admin/{groupName}?cacheBytes=N  修改 group 的 cacheBytes
+  DELETE /_cache_admin/{groupName}               清空 group
+  GET    /_cache_admin/{groupName}/keys?offset=0&limit=100
+  GET    /_cache_admin/{groupName}/keys/{key}    查看 key 的元信息
+  DELETE /_cache_admin/{groupName}/keys/{key}    删除 key
+  GET    /_cache_admin/{groupName}/hotkeys       当前节点上的热点 key，需要 Group.SetHotKeys
+*/
+
+const (
+	defaultAdminPath  = "/_cache_admin/"
+	defaultKeysLimit  = 100
+	maxKeysLimit      = 1000
+	keysPathSeparator = "/keys"
+	hotKeysSuffix     = "/hotkeys"
+)
+
+// Admin 运维使用的管理接口，只操作当前节点的本地缓存
+type Admin struct {
+	basePath string
+
+	// Registry 为 nil 时使用 DefaultRegistry
+	Registry *Registry
+}
+
+// GroupInfo group 的概况
+type GroupInfo struct {
+	Name       string `json:"name"`
+	Bytes      int64  `json:"bytes"`
+	CacheBytes int64  `json:"cacheBytes"`
+	Items      int    `json:"items"`
+}
+
+// KeyInfo key 的元信息
+type KeyInfo struct {
+	Key        string    `json:"key"`
+	Size       int       `json:"size"`
+	Age        string    `json:"age"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+// HotKeyReport 当前节点上的热点 key，按照访问次数从大到小排序
+type HotKeyReport struct {
+	Group string       `json:"group"`
+	Keys  []HotKeyInfo `json:"keys"`
+}
+
+// HotKeyInfo Hot 表示访问次数达到了 HotKeyOptions.Threshold
+type HotKeyInfo struct {
+	HotKey
+	Hot bool `json:"hot"`
+}
+
+// KeyPage 分页返回的 key
+type KeyPage struct {
+	Total  int      `json:"total"`
+	Offset int      `json:"offset"`
+	Keys   []string `json:"keys"`
+}
+
+func NewAdmin() *Admin {
+	return &Admin{basePath: defaultAdminPath}
+}
+
+// ServeHTTP impl http url handler
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, a.basePath) {
+		http.NotFound(w, r)
+		return
+	}
+	path := r.URL.Path[len(a.basePath):]
+	if path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		infos := make([]GroupInfo, 0)
+		for _, g := range a.Registry.orDefault().Groups() {
+			infos = append(infos, groupInfo(g))
+		}
+		writeJSON(w, infos)
+		return
+	}
+
+	groupName, rest := path, ""
+	if idx := strings.Index(path, keysPathSeparator); idx >= 0 {
+		groupName, rest = path[:idx], path[idx+len(keysPathSeparator):]
+	} else if strings.HasSuffix(path, hotKeysSuffix) {
+		// 只匹配 {groupName}/hotkeys，以 /hotkeys 结尾的 key 仍然按照 key 处理
+		a.handleHotKeys(path[:len(path)-len(hotKeysSuffix)], w, r)
+		return
+	}
+	group := a.Registry.orDefault().GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "no such group: "+groupName, http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case path == groupName:
+		a.handleGroup(group, w, r)
+	case rest == "" || rest == "/":
+		a.handleKeys(group, w, r)
+	case rest[0] == '/':
+		a.handleKey(group, rest[1:], w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *Admin) handleGroup(g *Group, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		g.mainCache.clear()
+	case http.MethodPut:
+		n, err := strconv.ParseInt(r.URL.Query().Get("cacheBytes"), 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "bad cacheBytes", http.StatusBadRequest)
+			return
+		}
+		g.SetCacheBytes(n)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, groupInfo(g))
+}
+
+func (a *Admin) handleKeys(g *Group, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = defaultKeysLimit
+	}
+	if limit > maxKeysLimit {
+		limit = maxKeysLimit
+	}
+	keys, total := g.mainCache.keys(offset, limit)
+	if keys == nil {
+		keys = []string{}
+	}
+	writeJSON(w, KeyPage{Total: total, Offset: offset, Keys: keys})
+}
+
+func (a *Admin) handleKey(g *Group, key string, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		info, ok := g.mainCache.stat(key)
+		if !ok {
+			http.Error(w, "no such key: "+key, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, KeyInfo{
+			Key:        info.Key,
+			Size:       info.Size,
+			Age:        time.Since(info.Added).String(),
+			LastAccess: info.Accessed,
+		})
+	case http.MethodDelete:
+		if !g.mainCache.remove(key) {
+			http.Error(w, "no such key: "+key, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *Admin) handleHotKeys(groupName string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	group := a.Registry.orDefault().GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "no such group: "+groupName, http.StatusNotFound)
+		return
+	}
+	keys := group.HotKeys()
+	if keys == nil {
+		http.Error(w, "hot key tracking is not enabled", http.StatusNotFound)
+		return
+	}
+	report := HotKeyReport{Group: groupName, Keys: make([]HotKeyInfo, len(keys))}
+	for i, k := range keys {
+		report.Keys[i] = HotKeyInfo{HotKey: k, Hot: group.IsHot(k.Key)}
+	}
+	writeJSON(w, report)
+}
+
+func groupInfo(g *Group) GroupInfo {
+	bytes, cacheBytes, items := g.mainCache.usage()
+	return GroupInfo{Name: g.name, Bytes: bytes, CacheBytes: cacheBytes, Items: items}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
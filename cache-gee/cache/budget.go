@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"log"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const defaultBudgetInterval = 10 * time.Second
+
+// MemoryBudget Registry 中所有 group 共享的内存预算
+// 每个周期按照 group 的命中次数重新分配 cacheBytes，命中越多的 group 分到的内存越多，
+// 没有因为容量不足淘汰过缓存的 group 最多分到已使用内存的两倍
+type MemoryBudget struct {
+	TotalBytes int64 // 所有 group 的 cacheBytes 之和的上限
+
+	// HeapSoftLimit runtime.MemStats.HeapAlloc 超过它时，按照超出的比例收缩所有 group，0 表示不检查
+	HeapSoftLimit uint64
+
+	// Interval 重新分配的间隔，默认 10s
+	Interval time.Duration
+}
+
+// cacheBounds group 在预算中的上下限
+type cacheBounds struct {
+	min, max int64
+}
+
+// SetCacheBounds 设置开启 MemoryBudget 时 group 的 cacheBytes 上下限，max 为 0 表示不限制
+// 即使超出了总预算也会保证 min
+func (g *Group) SetCacheBounds(min, max int64) {
+	g.bounds.Store(cacheBounds{min: min, max: max})
+}
+
+// CacheBytes 返回 group 当前允许使用的最大内存
+func (g *Group) CacheBytes() int64 {
+	_, maxBytes, _ := g.mainCache.usage()
+	return maxBytes
+}
+
+// SetMemoryBudget 开启内存预算，并在后台定期调用 Rebalance，b 为 nil 时关闭
+// 开启之后 group 的 cacheBytes 由预算决定，SetCacheBytes 的修改会在下一次分配时被覆盖
+func (r *Registry) SetMemoryBudget(b *MemoryBudget) {
+	var bg *budgeter
+	if b != nil {
+		bg = newBudgeter(r, *b)
+	}
+	r.budgetMtx.Lock()
+	old := r.budget
+	r.budget = bg
+	r.budgetMtx.Unlock()
+	if old != nil {
+		old.close()
+	}
+	if bg != nil {
+		bg.rebalance()
+		go bg.run()
+	}
+}
+
+// Rebalance 立即按照预算重新分配一次，没有开启 MemoryBudget 时什么也不做
+func (r *Registry) Rebalance() {
+	r.budgetMtx.Lock()
+	bg := r.budget
+	r.budgetMtx.Unlock()
+	if bg != nil {
+		bg.rebalance()
+	}
+}
+
+type budgeter struct {
+	r    *Registry
+	opts MemoryBudget
+
+	mtx  sync.Mutex
+	last map[*Group]budgetSample // 上一次分配时的统计
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// budgetSample 一个 group 在分配时的统计
+type budgetSample struct {
+	hits, evictions int64
+}
+
+func newBudgeter(r *Registry, opts MemoryBudget) *budgeter {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultBudgetInterval
+	}
+	return &budgeter{
+		r:    r,
+		opts: opts,
+		last: make(map[*Group]budgetSample),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (b *budgeter) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.rebalance()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *budgeter) close() {
+	close(b.stop)
+	<-b.done
+}
+
+// budgetShare 一个 group 参与分配时的状态
+type budgetShare struct {
+	g      *Group
+	weight float64
+	min    int64
+	cap    int64 // 这一次最多分到的内存
+	alloc  int64
+}
+
+func (b *budgeter) rebalance() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	groups := b.r.Groups()
+	shares := make([]*budgetShare, 0, len(groups))
+	last := make(map[*Group]budgetSample, len(groups))
+	var used int64
+	for _, g := range groups {
+		bytes, _, _ := g.mainCache.usage()
+		used += bytes
+		sample := budgetSample{hits: g.Stats.CacheHits.Get(), evictions: g.mainCache.evicted()}
+		prev := b.last[g]
+		last[g] = sample
+
+		bounds, _ := g.bounds.Load().(cacheBounds)
+		s := &budgetShare{
+			g: g,
+			// 加一使得还没有命中的 group 也能分到一部分
+			weight: float64(sample.hits-prev.hits) + 1,
+			// cacheBytes 为 0 表示不限制，至少保留 1 byte
+			min: max64(bounds.min, 1),
+			cap: math.MaxInt64,
+		}
+		if bounds.max > 0 {
+			s.cap = bounds.max
+		}
+		if sample.evictions == prev.evictions {
+			// 没有容量不足的压力，不需要更多的内存
+			s.cap = min64(s.cap, max64(2*bytes, s.min))
+		}
+		s.cap = max64(s.cap, s.min)
+		shares = append(shares, s)
+	}
+	b.last = last
+
+	total := b.opts.TotalBytes
+	if limit := b.opts.HeapSoftLimit; limit > 0 {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		if ms.HeapAlloc > limit {
+			// 按照超出的比例收缩已经使用的内存
+			shrunk := int64(float64(min64(total, used)) * float64(limit) / float64(ms.HeapAlloc))
+			log.Printf("[GeeCache] heap %d exceeds soft limit %d, shrinking caches to %d bytes", ms.HeapAlloc, limit, shrunk)
+			total = shrunk
+		}
+	}
+
+	distribute(shares, total)
+	for _, s := range shares {
+		s.g.mainCache.resize(s.alloc)
+	}
+}
+
+// distribute 先满足每个 group 的 min，剩余的按照 weight 分配，超出 cap 的部分分给其他 group
+func distribute(shares []*budgetShare, total int64) {
+	remaining := total
+	active := make([]*budgetShare, 0, len(shares))
+	for _, s := range shares {
+		s.alloc = s.min
+		remaining -= s.min
+		if s.alloc < s.cap {
+			active = append(active, s)
+		}
+	}
+	for remaining > 0 && len(active) > 0 {
+		var weights float64
+		for _, s := range active {
+			weights += s.weight
+		}
+		next := active[:0]
+		var given int64
+		for _, s := range active {
+			share := int64(float64(remaining) * s.weight / weights)
+			if share >= s.cap-s.alloc {
+				given += s.cap - s.alloc
+				s.alloc = s.cap
+				continue
+			}
+			s.alloc += share
+			given += share
+			next = append(next, s)
+		}
+		remaining -= given
+		if len(next) == len(active) {
+			// 没有 group 达到上限，剩下的只是取整的误差
+			return
+		}
+		active = next
+	}
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,25 +1,184 @@
 package cache
 
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// chunkSize 超过它的缓存值按照这个大小分块存储，避免一次分配整块内存
+const chunkSize = 64 << 10
+
 // ByteView 抽象了一个只读数据结构 ByteView 用来表示缓存值，是 GeeCache 主要的数据结构之一。
 type ByteView struct {
-	b []byte // 存储真实的缓存值，为了支持任意数据类型
+	b      []byte    // 存储真实的缓存值，为了支持任意数据类型
+	chunks [][]byte  // 分块存储的缓存值，不为 nil 时 b 为空
+	n      int       // chunks 的总长度
+	e      time.Time // 过期时间，零值表示永不过期
+	gen    int64     // 写入时 group 的 generation
+	tags   []string  // 写入缓存时建立 tag 索引，之后由 cache.keyTags 记录
+	x      *extra    // 版本等元信息，没有时为 nil
+	stale  bool      // stale-on-error 返回的旧值，不会写入缓存
+	cas    uint64    // owner 写入缓存时分配的版本，0 表示没有
+}
+
+// newByteView 拷贝 b，超过 chunkSize 时分块存储
+func newByteView(b []byte, expire time.Time, gen int64) ByteView {
+	if len(b) <= chunkSize {
+		return ByteView{b: cloneBytes(b), e: expire, gen: gen}
+	}
+	v := ByteView{n: len(b), e: expire, gen: gen}
+	for len(b) > 0 {
+		n := chunkSize
+		if n > len(b) {
+			n = len(b)
+		}
+		v.chunks = append(v.chunks, cloneBytes(b[:n]))
+		b = b[n:]
+	}
+	return v
+}
+
+// readByteView 从 r 中读取缓存值，超过 chunkSize 时分块存储
+func readByteView(r io.Reader) (ByteView, error) {
+	var v ByteView
+	for eof := false; !eof; {
+		chunk := make([]byte, chunkSize)
+		n := 0
+		for n < chunkSize {
+			m, err := r.Read(chunk[n:])
+			n += m
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err != nil {
+				return ByteView{}, err
+			}
+		}
+		if n == chunkSize {
+			v.chunks = append(v.chunks, chunk)
+		} else if n > 0 {
+			// 最后一块通常没有填满，拷贝一份避免占用整块的内存
+			v.chunks = append(v.chunks, cloneBytes(chunk[:n]))
+		}
+		v.n += n
+	}
+	if len(v.chunks) <= 1 {
+		// 只有一块时不需要分块存储
+		var b []byte
+		if len(v.chunks) == 1 {
+			b = v.chunks[0]
+		}
+		return ByteView{b: b}, nil
+	}
+	return v, nil
+}
+
+// Expire 返回过期时间，零值表示永不过期
+func (bv ByteView) Expire() time.Time {
+	return bv.e
+}
+
+// Meta 返回缓存值的元信息
+func (bv ByteView) Meta() Meta {
+	m := Meta{Expire: bv.e}
+	if bv.x != nil {
+		m.Version = bv.x.version
+		m.ContentType = bv.x.contentType
+		m.ContentEncoding = bv.x.contentEncoding
+		m.Flags = bv.x.flags
+	}
+	return m
+}
+
+// withMeta 返回设置了元信息的 ByteView
+func (bv ByteView) withMeta(m Meta) ByteView {
+	bv.e = m.Expire
+	bv.x = newExtra(m)
+	return bv
+}
+
+// Stale 为 true 时表示所有加载方式都失败，返回的是已经过期或者被淘汰的旧值
+func (bv ByteView) Stale() bool {
+	return bv.stale
+}
+
+// expired 判断在 now 时是否已经过期
+func (bv ByteView) expired(now time.Time) bool {
+	return !bv.e.IsZero() && !now.Before(bv.e)
 }
 
 // String toString impl Stringer
 func (bv ByteView) String() string {
+	if bv.chunks != nil {
+		return string(bv.ByteSlice())
+	}
 	return string(bv.b)
 }
 
 // Len 返回当前view的长度  impl Value
 func (bv ByteView) Len() int {
+	if bv.chunks != nil {
+		return bv.n
+	}
 	return len(bv.b)
 }
 
 // ByteSlice 返回一个拷贝，防止缓存值被外部程序修改。
 func (bv ByteView) ByteSlice() []byte {
+	if bv.chunks != nil {
+		return bytes.Join(bv.chunks, nil)
+	}
 	return cloneBytes(bv.b)
 }
 
+// Reader 返回读取缓存值的 io.Reader，不会拷贝数据
+func (bv ByteView) Reader() io.Reader {
+	if bv.chunks != nil {
+		return &chunkReader{chunks: bv.chunks}
+	}
+	return bytes.NewReader(bv.b)
+}
+
+// WriteTo 将缓存值写入 w  impl io.WriterTo
+func (bv ByteView) WriteTo(w io.Writer) (int64, error) {
+	if bv.chunks == nil {
+		n, err := w.Write(bv.b)
+		return int64(n), err
+	}
+	var total int64
+	for _, chunk := range bv.chunks {
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// chunkReader 依次读取每一块，chunks 与 ByteView 共享，不能修改
+type chunkReader struct {
+	chunks [][]byte
+	off    int // 当前块已经读取的长度
+}
+
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for n < len(p) && len(r.chunks) > 0 {
+		c := copy(p[n:], r.chunks[0][r.off:])
+		n += c
+		r.off += c
+		if r.off == len(r.chunks[0]) {
+			r.chunks, r.off = r.chunks[1:], 0
+		}
+	}
+	if n == 0 && len(r.chunks) == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
 // cloneBytes 克隆b中的数据，返回一个数据相同的byte数组
 func cloneBytes(b []byte) []byte {
 	bytes := make([]byte, len(b))
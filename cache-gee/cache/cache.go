@@ -1,9 +1,15 @@
 package cache
 
 import (
+	"log"
+	"math"
+	"strconv"
 	"sync"
+	"time"
 
+	"cache/disk"
 	"cache/lru"
+	"cache/slab"
 )
 
 // cache 添加并发的锁控制，在Cache的继承上包装一层
@@ -11,34 +17,791 @@ type cache struct {
 	mtx        sync.Mutex // 锁
 	lru        *lru.Cache // lru.Cache
 	cacheBytes int64
+	evictions  int64       // 因为容量不足被淘汰的次数
+	reason     EvictReason // 当前操作触发 OnEvicted 的原因
+
+	// onEvict 不为 nil 时，被淘汰的 key 会在释放锁之后通过它通知出去
+	onEvict func(key string, reason EvictReason)
+	pending []evictedKey
+
+	tags    map[string]map[string]struct{} // tag -> keys，与 lru 中的缓存项同步增删
+	keyTags map[string][]string            // key -> tags，只记录带有 tag 的 key
+
+	arena *slab.Arena // 不为 nil 时小的缓存值保存在 arena 中
+
+	// disk 不为 nil 时因为容量不足被淘汰的缓存值写入磁盘，命中时再移回 lru
+	// 同一个 key 只会在其中一层，tag 索引同时记录两层中的 key
+	disk *disk.Store
+
+	// demotions 被淘汰、等待写入磁盘的缓存值，释放锁之后按顺序写入，写入以及压缩不会阻塞其他操作；
+	// writing 为 true 时已经有一个调用在写入，同时只有一个，同一个 key 的写入不会乱序。
+	// demoting 记录其中仍然有效的，期间被重新写入或者删除的 key 从中删除；
+	// inflight 记录每个 key 还没有写完的数量，包括已经无效的，写完之前不从磁盘读取这个 key
+	demotions []*demotion
+	writing   bool
+	demoting  map[string]*demotion
+	inflight  map[string]int
+
+	dropMtx sync.Mutex
+	drops   []string // 因为 MaxBytes 被磁盘丢弃的 key，写入磁盘之后删除它们的 tag 索引
+
+	// stale 不为 nil 时保留因为容量不足被淘汰或者已经过期的缓存值，加载失败时使用
+	stale      *lru.Cache
+	staleGrace time.Duration
+}
+
+// staleValue 保留的旧值，until 之后不再使用
+type staleValue struct {
+	v     ByteView
+	tags  []string
+	until time.Time
+}
+
+func (v staleValue) Len() int {
+	return v.v.Len()
+}
+
+// slabValue 保存在 arena 中的缓存值，lru 中只记录位置，不包含任何指针
+// 有 extra 时编码之后保存在缓存值的后面
+type slabValue struct {
+	ref    slab.Ref
+	n      uint32 // 缓存值的长度，之后是编码的 extra
+	expire int64  // unix nano
+	gen    int64
+	cas    uint64
+}
+
+// Len 按照 size class 的大小计算，取整的部分同样占用 cacheBytes
+func (v slabValue) Len() int {
+	return v.ref.Cap()
+}
+
+// demotion 等待写入磁盘的缓存值
+type demotion struct {
+	key string
+	b   []byte // encodeDiskValue 编码之后的值
+}
+
+type evictedKey struct {
+	key    string
+	reason EvictReason
 }
 
 func (c *cache) add(key string, value ByteView) {
 	c.mtx.Lock()
-	defer c.mtx.Unlock() // 方法结束的时候解锁
+	c.addLocked(key, value)
+	c.unlock()
+}
+
+// compareAndAdd key 当前的版本等于 expected 时写入 value，返回 false 以及当前的版本。
+// 没有缓存、已经过期或者失效的 key 版本为 0
+func (c *cache) compareAndAdd(key string, expected uint64, value ByteView, minGen int64) (uint64, bool) {
+	c.mtx.Lock()
+	defer c.unlock()
+
+	var current uint64
+	if c.lru != nil {
+		if v, ok := c.lru.Peek(key); ok {
+			if old := c.view(v); !old.expired(time.Now()) && old.gen >= minGen {
+				current = old.cas
+			}
+		}
+	}
+	if current != expected {
+		return current, false
+	}
+	c.addLocked(key, value)
+	return current, true
+}
+
+// touch 只修改缓存值的过期时间，没有缓存、已经过期或者失效时返回 false
+func (c *cache) touch(key string, expire time.Time, minGen int64) bool {
+	c.mtx.Lock()
+	defer c.unlock()
+	if c.lru == nil {
+		return false
+	}
+	v, ok := c.lru.Peek(key)
+	if !ok {
+		return false
+	}
+	value := c.view(v)
+	if value.expired(time.Now()) || value.gen < minGen {
+		return false
+	}
+	value.e = expire
+	value.tags = c.keyTags[key]
+	c.addLocked(key, value)
+	return true
+}
+
+// incr 把 key 对应的计数器加上 delta，返回修改之后的值。
+// 没有缓存、已经过期或者失效的 key 从 0 开始，过期时间为 expire；已有的计数器保留原来的过期时间以及 tag
+func (c *cache) incr(key string, delta int64, expire time.Time, minGen int64) (int64, bool) {
+	c.mtx.Lock()
+	defer c.unlock()
+	initLur(c)
+
+	var n int64
+	var tags []string
+	if v, ok := c.lru.Peek(key); ok {
+		if old := c.view(v); !old.expired(time.Now()) && old.gen >= minGen {
+			var err error
+			if n, err = strconv.ParseInt(old.String(), 10, 64); err != nil {
+				return 0, false
+			}
+			expire, tags = old.e, c.keyTags[key]
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, false
+	}
+	n += delta
+	value := newByteView(strconv.AppendInt(nil, n, 10), expire, minGen)
+	value.cas = nextVersion()
+	value.tags = tags
+	c.addLocked(key, value)
+	return n, true
+}
+
+func (c *cache) addLocked(key string, value ByteView) {
 	initLur(c)
-	c.lru.Add(key, value)
+	if old, ok := c.lru.Peek(key); ok {
+		// 覆盖写入不会触发 OnEvicted
+		c.unindex(key)
+		c.free(old)
+	} else if c.disk != nil {
+		// 磁盘上以及正在写入磁盘的旧值不再有效
+		delete(c.demoting, key)
+		c.unindex(key)
+		c.diskDelete(key)
+	}
+	if c.stale != nil {
+		c.stale.Remove(key)
+	}
+	// 在 Add 之前建立索引，value 在 Add 中被立即淘汰时索引也会被删除
+	c.index(key, value.tags)
+	c.insert(key, value)
 }
 
-func (c *cache) get(key string) (value ByteView, ok bool) {
+// insert 写入 lru，超出 cacheBytes 时淘汰最旧的缓存
+func (c *cache) insert(key string, value ByteView) {
+	c.lru.Add(key, c.store(value))
+	c.evictStranded()
+}
+
+// evictStranded 开启 slab 时 Share 之后不能复用的空间同样计算在 cacheBytes 中，
+// 超出时继续淘汰最旧的缓存，直到它们所在的 slab 被归还
+func (c *cache) evictStranded() {
+	if c.arena == nil {
+		return
+	}
+	max := c.lru.MaxBytes()
+	for max != 0 && c.lru.Len() > 0 && c.lru.Bytes()+c.arena.Stats().StrandedBytes > max {
+		c.lru.RemoveOldest()
+	}
+}
+
+// store 开启 slab 时把缓存值拷贝到 arena 中，放不下的仍然保存在堆上
+func (c *cache) store(value ByteView) lru.Value {
+	value.tags = nil // 已经记录在 keyTags 中
+	if c.arena == nil || value.chunks != nil {
+		return value
+	}
+	data := value.b
+	if value.x != nil {
+		data = appendExtra(cloneBytes(value.b), value.x)
+	}
+	ref, ok := c.arena.Alloc(len(data))
+	if !ok {
+		return value
+	}
+	copy(c.arena.Bytes(ref), data)
+	return slabValue{ref: ref, n: uint32(len(value.b)), expire: unixNano(value.e), gen: value.gen, cas: value.cas}
+}
+
+// view 将 lru 中的值转换为 ByteView，arena 中的值不会拷贝。
+// arena 中的空间在释放之后会被复用，返回的 ByteView 只在持有锁时有效，返回给调用方之前需要 share
+func (c *cache) view(v lru.Value) ByteView {
+	if sv, ok := v.(slabValue); ok {
+		data := c.arena.Bytes(sv.ref)
+		v := ByteView{
+			b:   data[:sv.n:sv.n],
+			e:   fromUnixNano(sv.expire),
+			gen: sv.gen,
+			cas: sv.cas,
+		}
+		if int(sv.n) < len(data) {
+			v.x = decodeExtra(data[sv.n:])
+		}
+		return v
+	}
+	return v.(ByteView)
+}
+
+// share 之后 view 返回的 ByteView 可以一直持有，slab 中的空间不会被复用
+func (c *cache) share(v lru.Value) {
+	if sv, ok := v.(slabValue); ok {
+		c.arena.Share(sv.ref)
+	}
+}
+
+func (c *cache) free(v lru.Value) {
+	if sv, ok := v.(slabValue); ok {
+		c.arena.Free(sv.ref)
+	}
+}
+
+// enableSlabs 之后写入的缓存值保存在 arena 中
+func (c *cache) enableSlabs(opts slab.Options) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.arena != nil {
+		return false
+	}
+	c.arena = slab.New(opts)
+	return true
+}
+
+// slabStats 没有开启 slab 时返回零值
+func (c *cache) slabStats() slab.Stats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.arena == nil {
+		return slab.Stats{}
+	}
+	return c.arena.Stats()
+}
+
+func (c *cache) index(key string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	if c.tags == nil {
+		c.tags = make(map[string]map[string]struct{})
+		c.keyTags = make(map[string][]string)
+	}
+	c.keyTags[key] = tags
+	for _, tag := range tags {
+		keys := c.tags[tag]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// tagsOf 返回 key 的 tag
+func (c *cache) tagsOf(key string) []string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return cloneTags(c.keyTags[key])
+}
+
+func (c *cache) unindex(key string) {
+	tags, ok := c.keyTags[key]
+	if !ok {
+		return
+	}
+	delete(c.keyTags, key)
+	for _, tag := range tags {
+		if keys := c.tags[tag]; keys != nil {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+// removeTag 删除带有 tag 的所有 key，返回删除的数量
+func (c *cache) removeTag(tag string) int {
+	c.mtx.Lock()
+	defer c.unlock()
+	keys := make([]string, 0, len(c.tags[tag]))
+	for key := range c.tags[tag] {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		c.removeLocked(key, EvictRemoved)
+	}
+	if c.stale != nil {
+		// 保留的旧值不在 tag 索引中，数量有限，直接遍历
+		for _, key := range c.stale.Keys(0, c.stale.Len()) {
+			if v, ok := c.stale.Peek(key); ok && hasTag(v.(staleValue).tags, tag) {
+				c.stale.Remove(key)
+			}
+		}
+	}
+	return len(keys)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// get 返回 key 对应的值，generation 小于 minGen 的值被当作已经失效
+// 内存中没有时从磁盘读取，读取时不持有锁
+func (c *cache) get(key string, minGen int64) (ByteView, bool) {
+	value, ok, store := c.lookup(key, minGen)
+	if ok || store == nil {
+		return value, ok
+	}
+	return c.promote(store, key, minGen)
+}
+
+// lookup 只查找内存，同时返回磁盘缓存
+func (c *cache) lookup(key string, minGen int64) (value ByteView, ok bool, store *disk.Store) {
 	c.mtx.Lock()
-	defer c.mtx.Unlock() // 方法结束的时候解锁
+	defer c.unlock() // 方法结束的时候解锁
 
 	if c.lru == nil {
 		return
 	}
+	store = c.disk
 
 	// doGet
 	v, ok := c.lru.Get(key)
-	if ok {
-		return v.(ByteView), ok
+	if !ok {
+		if d := c.demoting[key]; d != nil {
+			value, ok = c.reclaim(d, minGen)
+			return value, ok, nil
+		}
+		if c.inflight[key] > 0 {
+			// 已经无效的值还在写入磁盘
+			return ByteView{}, false, nil
+		}
+		return
+	}
+	// 过期的数据直接删除
+	if value = c.view(v); value.expired(time.Now()) {
+		c.removeLocked(key, EvictExpired)
+		return ByteView{}, false, nil
+	}
+	if value.gen < minGen {
+		c.removeLocked(key, EvictInvalidated)
+		return ByteView{}, false, nil
+	}
+	// 命中时不拷贝，返回的 ByteView 直接引用 slab 中的空间
+	c.share(v)
+	return value, true, nil
+}
+
+// promote 从磁盘读取 key，仍然有效时移回内存
+func (c *cache) promote(store *disk.Store, key string, minGen int64) (ByteView, bool) {
+	b, seq, ok, err := store.Get(key)
+	if !ok && err == nil {
+		return ByteView{}, false
+	}
+	var value ByteView
+	if err == nil {
+		value, _, err = decodeDiskValue(b)
+	}
+	if err != nil {
+		log.Printf("[Cache] disk get %s: %v", key, err)
+	}
+
+	c.mtx.Lock()
+	defer c.unlock()
+	// 读取期间被覆盖、删除或者已经被其他调用移回内存
+	if c.disk != store {
+		return ByteView{}, false
+	}
+	if d := c.demoting[key]; d != nil {
+		return c.reclaim(d, minGen)
+	}
+	if c.inflight[key] > 0 {
+		return ByteView{}, false
+	}
+	if moved, _ := store.DeleteIf(key, seq); !moved {
+		return ByteView{}, false
+	}
+	// 已经从磁盘上删除，无效的值不再需要 tag 索引
+	if err != nil || value.expired(time.Now()) || value.gen < minGen {
+		c.unindex(key)
+		return ByteView{}, false
 	}
+	// 磁盘上没有保存版本，移回内存时分配新的版本
+	value.cas = nextVersion()
+	// tag 索引在写入磁盘时没有删除
+	c.insert(key, value)
+	return value, true
+}
+
+// demote 将因为容量不足被淘汰的缓存值加入 demotions，释放锁之后写入磁盘，加入时返回 true
+func (c *cache) demote(key string, v lru.Value) bool {
+	value := c.view(v)
+	if value.expired(time.Now()) {
+		return false
+	}
+	d := &demotion{key: key, b: encodeDiskValue(value, c.keyTags[key])}
+	if c.demoting == nil {
+		c.demoting = make(map[string]*demotion)
+	}
+	if c.inflight == nil {
+		c.inflight = make(map[string]int)
+	}
+	c.demoting[key] = d
+	c.inflight[key]++
+	c.demotions = append(c.demotions, d)
+	return true
+}
+
+// reclaim 把还没有写入磁盘的缓存值移回内存，调用方需要持有锁
+// 之后写入的磁盘记录在 writeDemotions 中被删除
+func (c *cache) reclaim(d *demotion, minGen int64) (ByteView, bool) {
+	delete(c.demoting, d.key)
+	value, _, err := decodeDiskValue(d.b)
+	if err != nil || value.expired(time.Now()) || value.gen < minGen {
+		c.unindex(d.key)
+		return ByteView{}, false
+	}
+	value.cas = nextVersion()
+	c.insert(d.key, value)
+	return value, true
+}
+
+// writeDemotions 在释放锁之后把 demotions 写入磁盘，直到队列为空，
+// 写入期间被重新写入内存或者删除的 key 再从磁盘上删除
+func (c *cache) writeDemotions() {
+	for {
+		c.mtx.Lock()
+		demotions, store := c.demotions, c.disk
+		c.demotions = nil
+		if len(demotions) == 0 || store == nil {
+			// 磁盘缓存已经关闭时丢弃
+			c.writing = false
+			c.mtx.Unlock()
+			return
+		}
+		c.mtx.Unlock()
+		c.writeBatch(store, demotions)
+	}
+}
+
+func (c *cache) writeBatch(store *disk.Store, demotions []*demotion) {
+	for _, d := range demotions {
+		seq, err := store.PutSeq(d.key, d.b)
+		if err != nil {
+			log.Printf("[Cache] disk put %s: %v", d.key, err)
+		}
+
+		c.mtx.Lock()
+		if c.inflight[d.key]--; c.inflight[d.key] <= 0 {
+			delete(c.inflight, d.key)
+		}
+		switch {
+		case c.demoting[d.key] != d:
+			if err == nil && c.disk == store {
+				if _, err := store.DeleteIf(d.key, seq); err != nil {
+					log.Printf("[Cache] disk delete %s: %v", d.key, err)
+				}
+			}
+		case err != nil:
+			delete(c.demoting, d.key)
+			c.unindex(d.key)
+		default:
+			delete(c.demoting, d.key)
+		}
+		c.unindexDrops()
+		c.mtx.Unlock()
+	}
+}
+
+// onDiskEvict Store 的 OnEvict，此时持有 Store 的锁，只记录下来
+func (c *cache) onDiskEvict(key string) {
+	c.dropMtx.Lock()
+	c.drops = append(c.drops, key)
+	c.dropMtx.Unlock()
+}
+
+// unindexDrops 删除被磁盘丢弃并且不在其他地方的 key 的 tag 索引，调用方需要持有锁
+func (c *cache) unindexDrops() {
+	c.dropMtx.Lock()
+	drops := c.drops
+	c.drops = nil
+	c.dropMtx.Unlock()
+	for _, key := range drops {
+		if _, ok := c.lru.Peek(key); ok || c.demoting[key] != nil {
+			continue
+		}
+		if c.disk == nil || !c.disk.Has(key) {
+			c.unindex(key)
+		}
+	}
+}
+
+func (c *cache) diskDelete(key string) {
+	if err := c.disk.Delete(key); err != nil {
+		log.Printf("[Cache] disk delete %s: %v", key, err)
+	}
+}
+
+// enableDisk 加载磁盘上已有的 key 到 tag 索引中，已经开启时返回 false
+func (c *cache) enableDisk(store *disk.Store) (bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.disk != nil {
+		return false, nil
+	}
+	initLur(c)
+	c.disk = store
+	store.OnEvict = c.onDiskEvict
+	return true, store.Scan(func(key string, b []byte) {
+		if _, tags, err := decodeDiskValue(b); err == nil {
+			c.index(key, tags)
+		}
+	})
+}
 
-	return
+// diskStats 没有开启磁盘缓存时返回零值
+func (c *cache) diskStats() disk.Stats {
+	c.mtx.Lock()
+	store := c.disk
+	c.mtx.Unlock()
+	if store == nil {
+		return disk.Stats{}
+	}
+	return store.Stats()
+}
+
+// close 关闭磁盘缓存，group 被删除之后调用
+func (c *cache) close() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.disk != nil {
+		c.disk.Close()
+		c.disk = nil
+	}
+}
+
+func (c *cache) remove(key string) bool {
+	c.mtx.Lock()
+	defer c.unlock()
+	if c.lru == nil {
+		return false
+	}
+	return c.removeLocked(key, EvictRemoved)
+}
+
+// removeVersion 只在 key 的版本仍然是 cas 时删除，不会删除之后写入的值
+func (c *cache) removeVersion(key string, cas uint64) bool {
+	c.mtx.Lock()
+	defer c.unlock()
+	if c.lru == nil {
+		return false
+	}
+	if v, ok := c.lru.Peek(key); !ok || c.view(v).cas != cas {
+		return false
+	}
+	return c.removeLocked(key, EvictRemoved)
+}
+
+func (c *cache) clear() {
+	c.mtx.Lock()
+	defer c.unlock()
+	if c.lru != nil {
+		c.reason = EvictPurged
+		c.lru.Clear()
+		c.reason = EvictCapacity
+		if c.arena != nil {
+			// 所有的缓存项都已经释放，归还 slab
+			c.arena.Reset()
+		}
+	}
+	if c.disk != nil {
+		if err := c.disk.Clear(); err != nil {
+			log.Printf("[Cache] disk clear: %v", err)
+		}
+		c.tags, c.keyTags = nil, nil
+		c.demoting = nil
+	}
+	if c.stale != nil {
+		c.stale.Clear()
+	}
+}
+
+// setStale 开启或者关闭 stale-on-error，之前保留的旧值会被清空
+func (c *cache) setStale(maxBytes int64, grace time.Duration, enabled bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stale, c.staleGrace = nil, grace
+	if enabled {
+		c.stale = lru.New(maxBytes, nil)
+	}
+}
+
+// keepStale 保留被淘汰或者过期的缓存值，调用方需要持有锁
+// 过期的值从过期时间开始计算 grace，被淘汰的从现在开始
+func (c *cache) keepStale(key string, value lru.Value) {
+	v := c.view(value)
+	if _, ok := value.(slabValue); ok {
+		// 旧值保留的时间不确定，拷贝出来，不让它一直占用整个 slab
+		v.b = cloneBytes(v.b)
+	}
+	now := time.Now()
+	until := now.Add(c.staleGrace)
+	if c.reason == EvictExpired && !v.e.IsZero() {
+		until = v.e.Add(c.staleGrace)
+	}
+	if !until.After(now) {
+		return
+	}
+	c.stale.Add(key, staleValue{v: v, tags: c.keyTags[key], until: until})
+}
+
+// getStale 返回保留的旧值，generation 小于 minGen 的不再使用
+func (c *cache) getStale(key string, minGen int64) (ByteView, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.stale == nil {
+		return ByteView{}, false
+	}
+	v, ok := c.stale.Get(key)
+	if !ok {
+		return ByteView{}, false
+	}
+	sv := v.(staleValue)
+	if !time.Now().Before(sv.until) || sv.v.gen < minGen {
+		c.stale.Remove(key)
+		return ByteView{}, false
+	}
+	value := sv.v
+	value.stale = true
+	return value, true
+}
+
+// removeLocked 主动删除 key，调用方需要持有锁
+func (c *cache) removeLocked(key string, reason EvictReason) bool {
+	c.reason = reason
+	defer func() { c.reason = EvictCapacity }()
+	if reason == EvictRemoved && c.stale != nil {
+		c.stale.Remove(key)
+	}
+	if c.lru.Remove(key) {
+		return true
+	}
+	if c.disk == nil {
+		return false
+	}
+	if c.demoting[key] != nil {
+		delete(c.demoting, key)
+	} else if c.inflight[key] > 0 || !c.disk.Has(key) {
+		return false
+	}
+	c.unindex(key)
+	c.diskDelete(key)
+	if c.onEvict != nil {
+		c.pending = append(c.pending, evictedKey{key: key, reason: reason})
+	}
+	return true
+}
+
+// onEvicted lru.Cache 的回调，调用时已经持有锁
+func (c *cache) onEvicted(key string, value lru.Value) {
+	// 写入磁盘的 key 保留 tag 索引
+	if c.reason != EvictCapacity || c.disk == nil || !c.demote(key, value) {
+		if c.stale != nil && (c.reason == EvictCapacity || c.reason == EvictExpired) {
+			c.keepStale(key, value)
+		}
+		c.unindex(key)
+	}
+	c.free(value)
+	if c.reason == EvictCapacity {
+		c.evictions++
+	}
+	if c.onEvict != nil {
+		c.pending = append(c.pending, evictedKey{key: key, reason: c.reason})
+	}
+}
+
+// unlock 释放锁，然后把持有锁期间被淘汰的缓存值写入磁盘，并通知被淘汰的 key
+func (c *cache) unlock() {
+	pending := c.pending
+	c.pending = nil
+	notify := c.onEvict
+	write := len(c.demotions) > 0 && !c.writing
+	if write {
+		c.writing = true
+	}
+	c.mtx.Unlock()
+
+	if write {
+		c.writeDemotions()
+	}
+
+	for _, e := range pending {
+		notify(e.key, e.reason)
+	}
+}
+
+// setOnEvict 设置淘汰通知的回调
+func (c *cache) setOnEvict(fn func(key string, reason EvictReason)) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.onEvict = fn
+}
+
+// evicted 返回因为容量不足被淘汰的次数
+func (c *cache) evicted() int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.evictions
+}
+
+// resize 修改 cacheBytes，超出的部分会立即被淘汰
+func (c *cache) resize(cacheBytes int64) {
+	c.mtx.Lock()
+	defer c.unlock()
+	c.cacheBytes = cacheBytes
+	if c.lru != nil {
+		c.lru.SetMaxBytes(cacheBytes)
+		c.evictStranded()
+	}
+}
+
+// usage 返回已经使用的内存、允许的最大内存以及缓存项的数量
+// 开启 slab 时已经使用的内存包括 slab 中空闲、还没有归还的空间
+func (c *cache) usage() (bytes, maxBytes int64, items int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.arena != nil {
+		st := c.arena.Stats()
+		bytes = st.SlabBytes - st.UsedBytes
+	}
+	if c.lru == nil {
+		return bytes, c.cacheBytes, 0
+	}
+	return bytes + c.lru.Bytes(), c.cacheBytes, c.lru.Len()
+}
+
+// keys 返回分页后的key以及key的总数
+func (c *cache) keys(offset, limit int) ([]string, int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.lru == nil {
+		return nil, 0
+	}
+	return c.lru.Keys(offset, limit), c.lru.Len()
+}
+
+func (c *cache) stat(key string) (lru.Info, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.lru == nil {
+		return lru.Info{}, false
+	}
+	return c.lru.Stat(key)
 }
 
 func initLur(c *cache) {
 	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
+		c.lru = lru.New(c.cacheBytes, c.onEvicted)
 	}
 }
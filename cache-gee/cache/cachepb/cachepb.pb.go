@@ -23,6 +23,8 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type Request struct {
 	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
 	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Generation           int64    `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"`
+	Local                bool     `protobuf:"varint,4,opt,name=local,proto3" json:"local,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -67,8 +69,31 @@ func (m *Request) GetKey() string {
 	return ""
 }
 
+func (m *Request) GetGeneration() int64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
+func (m *Request) GetLocal() bool {
+	if m != nil {
+		return m.Local
+	}
+	return false
+}
+
 type Response struct {
-	Value                []byte   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Value      []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Generation int64  `protobuf:"varint,2,opt,name=generation,proto3" json:"generation,omitempty"`
+	// 以下字段旧版本的节点不会设置，为零值时表示没有
+	Expire               int64    `protobuf:"varint,3,opt,name=expire,proto3" json:"expire,omitempty"`
+	Version              string   `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	ContentType          string   `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ContentEncoding      string   `protobuf:"bytes,6,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	Stale                bool     `protobuf:"varint,7,opt,name=stale,proto3" json:"stale,omitempty"`
+	Cas                  uint64   `protobuf:"varint,8,opt,name=cas,proto3" json:"cas,omitempty"`
+	Flags                uint32   `protobuf:"varint,9,opt,name=flags,proto3" json:"flags,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -106,23 +131,584 @@ func (m *Response) GetValue() []byte {
 	return nil
 }
 
+func (m *Response) GetGeneration() int64 {
+	if m != nil {
+		return m.Generation
+	}
+	return 0
+}
+
+func (m *Response) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *Response) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Response) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Response) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
+	}
+	return ""
+}
+
+func (m *Response) GetStale() bool {
+	if m != nil {
+		return m.Stale
+	}
+	return false
+}
+
+func (m *Response) GetCas() uint64 {
+	if m != nil {
+		return m.Cas
+	}
+	return 0
+}
+
+func (m *Response) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+type SetRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Expire               int64    `protobuf:"varint,4,opt,name=expire,proto3" json:"expire,omitempty"`
+	Tags                 []string `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	Version              string   `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	ContentType          string   `protobuf:"bytes,7,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ContentEncoding      string   `protobuf:"bytes,8,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	Fill                 bool     `protobuf:"varint,9,opt,name=fill,proto3" json:"fill,omitempty"`
+	Flags                uint32   `protobuf:"varint,10,opt,name=flags,proto3" json:"flags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetRequest) Reset()         { *m = SetRequest{} }
+func (m *SetRequest) String() string { return proto.CompactTextString(m) }
+func (*SetRequest) ProtoMessage()    {}
+func (*SetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{2}
+}
+
+func (m *SetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetRequest.Unmarshal(m, b)
+}
+func (m *SetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetRequest.Marshal(b, m, deterministic)
+}
+func (m *SetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetRequest.Merge(m, src)
+}
+func (m *SetRequest) XXX_Size() int {
+	return xxx_messageInfo_SetRequest.Size(m)
+}
+func (m *SetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetRequest proto.InternalMessageInfo
+
+func (m *SetRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *SetRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SetRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *SetRequest) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *SetRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *SetRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *SetRequest) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *SetRequest) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
+	}
+	return ""
+}
+
+func (m *SetRequest) GetFill() bool {
+	if m != nil {
+		return m.Fill
+	}
+	return false
+}
+
+func (m *SetRequest) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+// CasRequest owner 上缓存值的版本等于 expected 时写入
+type CasRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Expected             uint64   `protobuf:"varint,4,opt,name=expected,proto3" json:"expected,omitempty"`
+	Expire               int64    `protobuf:"varint,5,opt,name=expire,proto3" json:"expire,omitempty"`
+	Tags                 []string `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	Version              string   `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`
+	ContentType          string   `protobuf:"bytes,8,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ContentEncoding      string   `protobuf:"bytes,9,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
+	Flags                uint32   `protobuf:"varint,10,opt,name=flags,proto3" json:"flags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CasRequest) Reset()         { *m = CasRequest{} }
+func (m *CasRequest) String() string { return proto.CompactTextString(m) }
+func (*CasRequest) ProtoMessage()    {}
+func (*CasRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{3}
+}
+
+func (m *CasRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CasRequest.Unmarshal(m, b)
+}
+func (m *CasRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CasRequest.Marshal(b, m, deterministic)
+}
+func (m *CasRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CasRequest.Merge(m, src)
+}
+func (m *CasRequest) XXX_Size() int {
+	return xxx_messageInfo_CasRequest.Size(m)
+}
+func (m *CasRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CasRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CasRequest proto.InternalMessageInfo
+
+func (m *CasRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *CasRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *CasRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *CasRequest) GetExpected() uint64 {
+	if m != nil {
+		return m.Expected
+	}
+	return 0
+}
+
+func (m *CasRequest) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *CasRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *CasRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *CasRequest) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *CasRequest) GetContentEncoding() string {
+	if m != nil {
+		return m.ContentEncoding
+	}
+	return ""
+}
+
+func (m *CasRequest) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+type CasResponse struct {
+	Swapped              bool     `protobuf:"varint,1,opt,name=swapped,proto3" json:"swapped,omitempty"`
+	Cas                  uint64   `protobuf:"varint,2,opt,name=cas,proto3" json:"cas,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CasResponse) Reset()         { *m = CasResponse{} }
+func (m *CasResponse) String() string { return proto.CompactTextString(m) }
+func (*CasResponse) ProtoMessage()    {}
+func (*CasResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{4}
+}
+
+func (m *CasResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CasResponse.Unmarshal(m, b)
+}
+func (m *CasResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CasResponse.Marshal(b, m, deterministic)
+}
+func (m *CasResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CasResponse.Merge(m, src)
+}
+func (m *CasResponse) XXX_Size() int {
+	return xxx_messageInfo_CasResponse.Size(m)
+}
+func (m *CasResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CasResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CasResponse proto.InternalMessageInfo
+
+func (m *CasResponse) GetSwapped() bool {
+	if m != nil {
+		return m.Swapped
+	}
+	return false
+}
+
+func (m *CasResponse) GetCas() uint64 {
+	if m != nil {
+		return m.Cas
+	}
+	return 0
+}
+
+// IncrRequest 在 owner 上把 key 对应的计数器加上 delta
+type IncrRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Delta                int64    `protobuf:"varint,3,opt,name=delta,proto3" json:"delta,omitempty"`
+	Ttl                  int64    `protobuf:"varint,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IncrRequest) Reset()         { *m = IncrRequest{} }
+func (m *IncrRequest) String() string { return proto.CompactTextString(m) }
+func (*IncrRequest) ProtoMessage()    {}
+func (*IncrRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{5}
+}
+
+func (m *IncrRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrRequest.Unmarshal(m, b)
+}
+func (m *IncrRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrRequest.Marshal(b, m, deterministic)
+}
+func (m *IncrRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrRequest.Merge(m, src)
+}
+func (m *IncrRequest) XXX_Size() int {
+	return xxx_messageInfo_IncrRequest.Size(m)
+}
+func (m *IncrRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrRequest proto.InternalMessageInfo
+
+func (m *IncrRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *IncrRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *IncrRequest) GetDelta() int64 {
+	if m != nil {
+		return m.Delta
+	}
+	return 0
+}
+
+func (m *IncrRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+type IncrResponse struct {
+	Value                int64    `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	NotInteger           bool     `protobuf:"varint,2,opt,name=not_integer,json=notInteger,proto3" json:"not_integer,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IncrResponse) Reset()         { *m = IncrResponse{} }
+func (m *IncrResponse) String() string { return proto.CompactTextString(m) }
+func (*IncrResponse) ProtoMessage()    {}
+func (*IncrResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{6}
+}
+
+func (m *IncrResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrResponse.Unmarshal(m, b)
+}
+func (m *IncrResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrResponse.Marshal(b, m, deterministic)
+}
+func (m *IncrResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrResponse.Merge(m, src)
+}
+func (m *IncrResponse) XXX_Size() int {
+	return xxx_messageInfo_IncrResponse.Size(m)
+}
+func (m *IncrResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrResponse proto.InternalMessageInfo
+
+func (m *IncrResponse) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *IncrResponse) GetNotInteger() bool {
+	if m != nil {
+		return m.NotInteger
+	}
+	return false
+}
+
+// TouchRequest 只修改 owner 上缓存值的过期时间
+type TouchRequest struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Expire               int64    `protobuf:"varint,3,opt,name=expire,proto3" json:"expire,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TouchRequest) Reset()         { *m = TouchRequest{} }
+func (m *TouchRequest) String() string { return proto.CompactTextString(m) }
+func (*TouchRequest) ProtoMessage()    {}
+func (*TouchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{7}
+}
+
+func (m *TouchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TouchRequest.Unmarshal(m, b)
+}
+func (m *TouchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TouchRequest.Marshal(b, m, deterministic)
+}
+func (m *TouchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TouchRequest.Merge(m, src)
+}
+func (m *TouchRequest) XXX_Size() int {
+	return xxx_messageInfo_TouchRequest.Size(m)
+}
+func (m *TouchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TouchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TouchRequest proto.InternalMessageInfo
+
+func (m *TouchRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *TouchRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *TouchRequest) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+type TouchResponse struct {
+	Found                bool     `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TouchResponse) Reset()         { *m = TouchResponse{} }
+func (m *TouchResponse) String() string { return proto.CompactTextString(m) }
+func (*TouchResponse) ProtoMessage()    {}
+func (*TouchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_65b4d2f9fe4de76d, []int{8}
+}
+
+func (m *TouchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TouchResponse.Unmarshal(m, b)
+}
+func (m *TouchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TouchResponse.Marshal(b, m, deterministic)
+}
+func (m *TouchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TouchResponse.Merge(m, src)
+}
+func (m *TouchResponse) XXX_Size() int {
+	return xxx_messageInfo_TouchResponse.Size(m)
+}
+func (m *TouchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TouchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TouchResponse proto.InternalMessageInfo
+
+func (m *TouchResponse) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*Request)(nil), "cachepb.Request")
 	proto.RegisterType((*Response)(nil), "cachepb.Response")
+	proto.RegisterType((*SetRequest)(nil), "cachepb.SetRequest")
+	proto.RegisterType((*CasRequest)(nil), "cachepb.CasRequest")
+	proto.RegisterType((*CasResponse)(nil), "cachepb.CasResponse")
+	proto.RegisterType((*IncrRequest)(nil), "cachepb.IncrRequest")
+	proto.RegisterType((*IncrResponse)(nil), "cachepb.IncrResponse")
+	proto.RegisterType((*TouchRequest)(nil), "cachepb.TouchRequest")
+	proto.RegisterType((*TouchResponse)(nil), "cachepb.TouchResponse")
 }
 
 func init() { proto.RegisterFile("cachepb.proto", fileDescriptor_65b4d2f9fe4de76d) }
 
 var fileDescriptor_65b4d2f9fe4de76d = []byte{
-	// 145 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4d, 0x4e, 0x4c, 0xce,
-	0x48, 0x2d, 0x48, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x87, 0x72, 0x95, 0x0c, 0xb9,
-	0xd8, 0x83, 0x52, 0x0b, 0x4b, 0x53, 0x8b, 0x4b, 0x84, 0x44, 0xb8, 0x58, 0xd3, 0x8b, 0xf2, 0x4b,
-	0x0b, 0x24, 0x18, 0x15, 0x18, 0x35, 0x38, 0x83, 0x20, 0x1c, 0x21, 0x01, 0x2e, 0xe6, 0xec, 0xd4,
-	0x4a, 0x09, 0x26, 0xb0, 0x18, 0x88, 0xa9, 0xa4, 0xc0, 0xc5, 0x11, 0x94, 0x5a, 0x5c, 0x90, 0x9f,
-	0x57, 0x9c, 0x0a, 0xd2, 0x53, 0x96, 0x98, 0x53, 0x9a, 0x0a, 0xd6, 0xc3, 0x13, 0x04, 0xe1, 0x18,
-	0x59, 0x70, 0x71, 0xb9, 0x83, 0x34, 0x3b, 0x83, 0x2c, 0x11, 0xd2, 0xe2, 0x62, 0x76, 0x4f, 0x2d,
-	0x11, 0x12, 0xd0, 0x83, 0x39, 0x01, 0x6a, 0xa1, 0x94, 0x20, 0x92, 0x08, 0xc4, 0xbc, 0x24, 0x36,
-	0xb0, 0xf3, 0x8c, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x0d, 0x21, 0x50, 0x8d, 0xaf, 0x00, 0x00,
-	0x00,
+	// 504 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x94, 0x4f, 0x6f, 0xd3, 0x4c,
+	0x10, 0xc6, 0xe5, 0xd8, 0x89, 0x9d, 0x49, 0xa2, 0x37, 0xef, 0x0a, 0xa1, 0x55, 0x0f, 0x60, 0x2c,
+	0x21, 0x19, 0x0e, 0x3d, 0xc0, 0x05, 0xce, 0x55, 0x55, 0xf5, 0xc2, 0x61, 0xe9, 0x15, 0x55, 0x5b,
+	0x67, 0xe2, 0x5a, 0x58, 0xbb, 0x8b, 0xbd, 0x2e, 0xcd, 0x37, 0xe0, 0xc8, 0x27, 0x46, 0x68, 0xff,
+	0x38, 0x31, 0xe0, 0x56, 0x2d, 0xe2, 0x36, 0xcf, 0x64, 0xf2, 0xec, 0xec, 0xcf, 0x33, 0x0b, 0xab,
+	0x82, 0x17, 0xd7, 0xa8, 0xae, 0x8e, 0x55, 0x23, 0xb5, 0x24, 0xb1, 0x97, 0x59, 0x09, 0x31, 0xc3,
+	0x2f, 0x1d, 0xb6, 0x9a, 0x3c, 0x81, 0x69, 0xd9, 0xc8, 0x4e, 0xd1, 0x20, 0x0d, 0xf2, 0x39, 0x73,
+	0x82, 0xac, 0x21, 0xfc, 0x8c, 0x3b, 0x3a, 0xb1, 0x39, 0x13, 0x92, 0x67, 0x00, 0x25, 0x0a, 0x6c,
+	0xb8, 0xae, 0xa4, 0xa0, 0x61, 0x1a, 0xe4, 0x21, 0x1b, 0x64, 0x8c, 0x4f, 0x2d, 0x0b, 0x5e, 0xd3,
+	0x28, 0x0d, 0xf2, 0x84, 0x39, 0x91, 0xfd, 0x08, 0x20, 0x61, 0xd8, 0x2a, 0x29, 0x5a, 0x34, 0x25,
+	0x37, 0xbc, 0xee, 0xd0, 0x1e, 0xb5, 0x64, 0x4e, 0xfc, 0x66, 0x3c, 0xf9, 0xc3, 0xf8, 0x29, 0xcc,
+	0xf0, 0x56, 0x55, 0x0d, 0xfa, 0x43, 0xbd, 0x22, 0x14, 0xe2, 0x1b, 0x6c, 0x5a, 0xf3, 0xa7, 0xc8,
+	0xb6, 0xd9, 0x4b, 0xf2, 0x02, 0x96, 0x85, 0x14, 0x1a, 0x85, 0xbe, 0xd4, 0x3b, 0x85, 0x74, 0x6a,
+	0x7f, 0x5e, 0xf8, 0xdc, 0xc5, 0x4e, 0x21, 0x79, 0x05, 0xeb, 0xbe, 0x04, 0x45, 0x21, 0x37, 0x95,
+	0x28, 0xe9, 0xcc, 0x96, 0xfd, 0xe7, 0xf3, 0xa7, 0x3e, 0x6d, 0xba, 0x6e, 0x35, 0xaf, 0x91, 0xc6,
+	0xee, 0x62, 0x56, 0x18, 0x40, 0x05, 0x6f, 0x69, 0x92, 0x06, 0x79, 0xc4, 0x4c, 0x68, 0xea, 0xb6,
+	0x35, 0x2f, 0x5b, 0x3a, 0x4f, 0x83, 0x7c, 0xc5, 0x9c, 0xc8, 0xbe, 0x4d, 0x00, 0x3e, 0xa2, 0x7e,
+	0x2c, 0xed, 0x3d, 0xaa, 0x70, 0x88, 0xea, 0x80, 0x22, 0xfa, 0x05, 0x05, 0x81, 0x48, 0x9b, 0x93,
+	0xa7, 0x69, 0x98, 0xcf, 0x99, 0x8d, 0x87, 0x78, 0x66, 0xf7, 0xe3, 0x89, 0x1f, 0x86, 0x27, 0x19,
+	0xc7, 0x43, 0x20, 0xda, 0x56, 0x75, 0x6d, 0x6f, 0x9d, 0x30, 0x1b, 0x1f, 0x50, 0xc0, 0x10, 0xc5,
+	0xf7, 0x09, 0xc0, 0x09, 0x6f, 0xff, 0x0d, 0x8a, 0x23, 0x48, 0xf0, 0x56, 0x61, 0xa1, 0x71, 0x63,
+	0x61, 0x44, 0x6c, 0xaf, 0x07, 0x98, 0xa6, 0xa3, 0x98, 0x66, 0xe3, 0x98, 0xe2, 0xfb, 0x31, 0x25,
+	0x0f, 0xc3, 0x34, 0xbf, 0x73, 0x8a, 0x46, 0x90, 0xbc, 0x87, 0x85, 0x25, 0xe2, 0x17, 0x84, 0x42,
+	0xdc, 0x7e, 0xe5, 0x4a, 0xe1, 0xc6, 0x42, 0x49, 0x58, 0x2f, 0xfb, 0x71, 0x9b, 0xec, 0xc7, 0x2d,
+	0xfb, 0x04, 0x8b, 0x73, 0x51, 0x34, 0x7f, 0x41, 0x73, 0x83, 0xb5, 0xe6, 0x7e, 0x99, 0x9c, 0x30,
+	0x75, 0x5a, 0xd7, 0x7e, 0xaa, 0x4c, 0x98, 0x9d, 0xc2, 0xd2, 0xd9, 0x8f, 0xed, 0x6e, 0xd8, 0x7f,
+	0x85, 0xe7, 0xb0, 0x10, 0x52, 0x5f, 0x56, 0x42, 0x63, 0x89, 0x8d, 0x3d, 0x27, 0x61, 0x20, 0xa4,
+	0x3e, 0x77, 0x99, 0xec, 0x03, 0x2c, 0x2f, 0x64, 0x57, 0x5c, 0x3f, 0xb6, 0xcd, 0x3b, 0x96, 0x3e,
+	0x7b, 0x09, 0x2b, 0xef, 0x77, 0xe8, 0x6b, 0x2b, 0x3b, 0xd1, 0x03, 0x73, 0xe2, 0xcd, 0x3b, 0x80,
+	0x33, 0xe3, 0x7c, 0x62, 0xde, 0x3b, 0xf2, 0x1a, 0xc2, 0x33, 0xd4, 0x64, 0x7d, 0xdc, 0xbf, 0x86,
+	0xbe, 0x9b, 0xa3, 0xff, 0x07, 0x19, 0xe7, 0x77, 0x35, 0xb3, 0x2f, 0xe5, 0xdb, 0x9f, 0x03, 0x00,
+	0xe8, 0x79, 0x86, 0xc8, 0x3a, 0x05, 0x00, 0x00,
 }
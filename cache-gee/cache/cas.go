@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "cache/cachepb"
+)
+
+// ErrVersionConflict CompareAndSet 时 owner 上的版本与期望的不一致，可以使用 errors.Is 判断
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError 包含了 owner 上当前的版本
+type VersionConflictError struct {
+	Group    string
+	Key      string
+	Expected uint64
+	Actual   uint64 // 0 表示 key 没有被缓存
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("group %s key %s: version conflict, expected %d, actual %d", e.Group, e.Key, e.Expected, e.Actual)
+}
+
+// Is 使 errors.Is(err, ErrVersionConflict) 成立
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// versionSeq 以启动时间为初始值，重启或者 owner 变化之后分配的版本也不会与之前的重复
+var versionSeq = uint64(time.Now().UnixNano())
+
+// nextVersion 分配一个新的版本，写入缓存以及从数据源加载时使用
+func nextVersion() uint64 {
+	return atomic.AddUint64(&versionSeq, 1)
+}
+
+// GetVersioned 与 Get 相同，同时返回缓存值的版本。
+// 版本由 owner 写入缓存时分配，每次写入都会变化；旧版本的节点返回的值版本为 0。
+// 缓存值从磁盘移回内存时也会分配新的版本，此时 CompareAndSet 返回冲突，重新获取即可
+func (g *Group) GetVersioned(key string) (ByteView, uint64, error) {
+	v, err := g.Get(key)
+	return v, v.cas, err
+}
+
+// CompareAndSet owner 上 key 的版本等于 expected 时写入 value，返回新的版本；
+// expected 为 0 表示只在 key 没有被缓存时写入。版本不一致时返回 *VersionConflictError。
+// 比较和写入缓存是原子的，成功之后与 Set 一样写入数据源，写入失败时删除缓存并返回错误。
+// 同一个 key 的 CompareAndSet 在 owner 上依次执行，数据源收到的写入与版本的顺序一致
+func (g *Group) CompareAndSet(key string, expected uint64, value []byte, expire time.Time, tags ...string) (uint64, error) {
+	return g.CompareAndSetEntry(key, expected, Entry{Value: value, Tags: tags, Meta: Meta{Expire: expire}})
+}
+
+// CompareAndSetEntry 与 CompareAndSet 相同，写入带有元信息的缓存
+func (g *Group) CompareAndSetEntry(key string, expected uint64, e Entry) (uint64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			return g.compareAndSetOnPeer(peer, key, expected, e)
+		}
+	}
+	return g.compareAndSetLocally(key, expected, e)
+}
+
+func (g *Group) compareAndSetOnPeer(peer PeerGetter, key string, expected uint64, e Entry) (uint64, error) {
+	cp, ok := peer.(CASPeerGetter)
+	if !ok {
+		return 0, fmt.Errorf("peer %s does not support compare-and-set", peerName(peer))
+	}
+	out := &pb.CasResponse{}
+	err := cp.CompareAndSet(&pb.CasRequest{
+		Group:           g.name,
+		Key:             key,
+		Value:           e.Value,
+		Expected:        expected,
+		Expire:          unixNano(e.Expire),
+		Tags:            e.Tags,
+		Version:         e.Version,
+		ContentType:     e.ContentType,
+		ContentEncoding: e.ContentEncoding,
+		Flags:           e.Flags,
+	}, out)
+	if err != nil {
+		return 0, err
+	}
+	if !out.GetSwapped() {
+		return 0, &VersionConflictError{Group: g.name, Key: key, Expected: expected, Actual: out.GetCas()}
+	}
+	return out.GetCas(), nil
+}
+
+func (g *Group) compareAndSetLocally(key string, expected uint64, e Entry) (uint64, error) {
+	// 写入数据源之前不释放，之后的 CompareAndSet 才能看到这次写入的版本
+	unlock := g.casLocks.lock(key)
+	defer unlock()
+
+	gen := g.Generation()
+	// 磁盘上的值先移回内存，得到它的版本
+	g.mainCache.get(key, gen)
+	v := entryView(e, gen)
+	if actual, ok := g.mainCache.compareAndAdd(key, expected, v, gen); !ok {
+		return 0, &VersionConflictError{Group: g.name, Key: key, Expected: expected, Actual: actual}
+	}
+	if err := g.writeStore(Write{Key: key, Entry: e}); err != nil {
+		// 期间被 Set 覆盖的值不删除
+		g.mainCache.removeVersion(key, v.cas)
+		return 0, err
+	}
+	g.Stats.Sets.Add(1)
+	return v.cas, nil
+}
+
+// casStripes keyLocks 中锁的数量
+const casStripes = 64
+
+// keyLocks 根据 key 的哈希选择一把锁，同一个 key 的操作依次执行
+type keyLocks [casStripes]sync.Mutex
+
+func (l *keyLocks) lock(key string) func() {
+	m := &l[crc32.ChecksumIEEE([]byte(key))%casStripes]
+	m.Lock()
+	return m.Unlock
+}
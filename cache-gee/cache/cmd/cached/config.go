@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Config cached 的配置文件
+type Config struct {
+	Self     string   `json:"self"`     // 本节点在 peers 中的地址，eg: http://localhost:8001
+	Listen   string   `json:"listen"`   // 节点间通信的监听地址，eg: :8001
+	API      string   `json:"api"`      // 对外提供服务的监听地址，为空时不启动
+	Admin    string   `json:"admin"`    // 管理接口以及 /metrics 的监听地址，为空时不启动
+	Redis    string   `json:"redis"`    // redis 协议的监听地址，为空时不启动
+	Memcache string   `json:"memcache"` // memcached 文本协议的监听地址，为空时不启动
+	Peers    []string `json:"peers"`    // 所有节点的地址，包括自己
+
+	BasePath    string   `json:"basePath"`    // 节点间通信的路径前缀，默认 /distributed_cache/
+	Replicas    int      `json:"replicas"`    // 每个节点的虚拟节点数量，默认 50
+	PeerTimeout Duration `json:"peerTimeout"` // 访问其他节点的超时时间，为空时不超时
+
+	Secret string     `json:"secret"` // 节点间请求签名使用的密钥
+	TLS    *TLSConfig `json:"tls"`
+
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+	// HandOffHotKeys 下线时把自己负责的热点 key 写入它们新的 owner
+	HandOffHotKeys bool          `json:"handOffHotKeys"`
+	Groups         []GroupConfig `json:"groups"`
+
+	// MemoryBudget 不为空时所有 group 共享内存预算，group 的 cacheBytes 只作为初始值
+	MemoryBudget *MemoryBudgetConfig `json:"memoryBudget"`
+}
+
+// MemoryBudgetConfig 对应 cache.MemoryBudget
+type MemoryBudgetConfig struct {
+	TotalBytes    int64    `json:"totalBytes"`
+	HeapSoftLimit uint64   `json:"heapSoftLimit"`
+	Interval      Duration `json:"interval"`
+}
+
+// TLSConfig 证书配置，均为 PEM 文件路径
+type TLSConfig struct {
+	Cert   string `json:"cert"`
+	Key    string `json:"key"`
+	CA     string `json:"ca"`
+	Mutual bool   `json:"mutual"`
+}
+
+type GroupConfig struct {
+	Name       string        `json:"name"`
+	CacheBytes int64         `json:"cacheBytes"`
+	MinBytes   int64         `json:"minBytes"` // 开启 memoryBudget 时的下限
+	MaxBytes   int64         `json:"maxBytes"` // 开启 memoryBudget 时的上限，0 表示不限制
+	Source     SourceConfig  `json:"source"`
+	Limits     *LimitsConfig `json:"limits"`
+	Hedge      *HedgeConfig  `json:"hedge"`
+	HotKeys    *HotKeyConfig `json:"hotKeys"`
+	Disk       *DiskConfig   `json:"disk"`
+	Stale      *StaleConfig  `json:"staleOnError"`
+
+	// WriteBehind 不为空时写入先更新缓存，之后在后台写入数据源，需要 source.writable
+	WriteBehind *WriteBehindConfig `json:"writeBehind"`
+}
+
+// WriteBehindConfig 对应 cache.WriteBehindOptions
+type WriteBehindConfig struct {
+	BatchSize     int      `json:"batchSize"`
+	FlushInterval Duration `json:"flushInterval"`
+	MaxPending    int      `json:"maxPending"`
+	MaxRetries    int      `json:"maxRetries"`
+	RetryBackoff  Duration `json:"retryBackoff"`
+}
+
+// HedgeConfig 对应 cache.HedgeOptions
+type HedgeConfig struct {
+	Percentile float64  `json:"percentile"`
+	MinDelay   Duration `json:"minDelay"`
+	MaxDelay   Duration `json:"maxDelay"`
+	AllowLocal bool     `json:"allowLocal"`
+}
+
+// DiskConfig 本地磁盘上的二级缓存，对应 disk.Options
+type DiskConfig struct {
+	Dir          string `json:"dir"`
+	MaxBytes     int64  `json:"maxBytes"`
+	SegmentBytes int64  `json:"segmentBytes"`
+}
+
+// StaleConfig 对应 cache.StaleOptions
+type StaleConfig struct {
+	MaxBytes int64    `json:"maxBytes"`
+	Grace    Duration `json:"grace"`
+}
+
+// HotKeyConfig 对应 cache.HotKeyOptions
+type HotKeyConfig struct {
+	K         int      `json:"k"`
+	HalfLife  Duration `json:"halfLife"`
+	Threshold uint32   `json:"threshold"`
+	Mirror    bool     `json:"mirror"`
+	MirrorTTL Duration `json:"mirrorTTL"`
+}
+
+// LimitsConfig 对应 cache.LoadLimits
+type LimitsConfig struct {
+	MaxConcurrent int      `json:"maxConcurrent"`
+	MaxQueue      int      `json:"maxQueue"`
+	MaxWait       Duration `json:"maxWait"`
+	Rate          float64  `json:"rate"`
+	Burst         int      `json:"burst"`
+}
+
+// SourceConfig 缓存未命中时的数据源
+// type=file: 从 path 目录下读取与 key 同名的文件，writable 为 true 时 Set/Remove 会写入文件
+// type=http: 请求 url + key
+type SourceConfig struct {
+	Type    string   `json:"type"`
+	Path    string   `json:"path"`
+	URL     string   `json:"url"`
+	Timeout Duration `json:"timeout"`
+
+	Writable bool `json:"writable"`
+}
+
+// Duration 支持在 json 中使用 "10s" 这样的写法
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func loadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{ShutdownTimeout: Duration(10 * time.Second)}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	return c, c.validate()
+}
+
+func (c *Config) validate() error {
+	if c.Self == "" || c.Listen == "" {
+		return fmt.Errorf("self and listen are required")
+	}
+	if len(c.Groups) == 0 {
+		return fmt.Errorf("at least one group is required")
+	}
+	for _, g := range c.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("group name is required")
+		}
+		switch g.Source.Type {
+		case "file":
+			if g.Source.Path == "" {
+				return fmt.Errorf("group %s: source path is required", g.Name)
+			}
+		case "http":
+			if g.Source.URL == "" {
+				return fmt.Errorf("group %s: source url is required", g.Name)
+			}
+		default:
+			return fmt.Errorf("group %s: unknown source type %q", g.Name, g.Source.Type)
+		}
+	}
+	return nil
+}
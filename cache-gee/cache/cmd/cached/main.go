@@ -0,0 +1,261 @@
+// cached 分布式缓存节点
+//
+// usage: cached -config cached.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"cache"
+	"cache/disk"
+)
+
+func newPool(c *Config) (*cache.HttpPool, error) {
+	opts := &cache.HttpPoolOptions{
+		Secret:   []byte(c.Secret),
+		BasePath: c.BasePath,
+		Replicas: c.Replicas,
+		Timeout:  time.Duration(c.PeerTimeout),
+
+		HandOffHotKeys: c.HandOffHotKeys,
+	}
+	if c.TLS != nil {
+		tlsOpts, err := cache.LoadTLSOptions(c.TLS.Cert, c.TLS.Key, c.TLS.CA, c.TLS.Mutual)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLS = tlsOpts
+	}
+	pool := cache.NewHttpPoolOpts(c.Self, opts)
+	pool.Set(c.Peers...)
+	return pool, nil
+}
+
+// apiHandler GET /api?group={groupName}&key={key}
+// 计数器 POST /api/incr?group={groupName}&key={key}&delta={delta}&ttl={ttl}
+func apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		group := cache.GetGroup(r.URL.Query().Get("group"))
+		if group == nil {
+			http.Error(w, "no such group", http.StatusNotFound)
+			return
+		}
+		stream, err := group.GetStream(r.URL.Query().Get("key"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer stream.Close()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		stream.Meta.WriteHeaders(w.Header())
+		if stream.Stale {
+			w.Header().Set("Warning", `110 - "Response is Stale"`)
+		}
+		io.Copy(w, stream)
+	})
+	// delta 默认为 1，可以是负数；ttl 只在创建计数器时生效，返回修改之后的值
+	mux.HandleFunc("/api/incr", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		group := cache.GetGroup(query.Get("group"))
+		if group == nil {
+			http.Error(w, "no such group", http.StatusNotFound)
+			return
+		}
+		delta, ttl := int64(1), time.Duration(0)
+		var err error
+		if s := query.Get("delta"); s != "" {
+			if delta, err = strconv.ParseInt(s, 10, 64); err != nil {
+				http.Error(w, "bad delta: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if s := query.Get("ttl"); s != "" {
+			if ttl, err = time.ParseDuration(s); err != nil {
+				http.Error(w, "bad ttl: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		n, err := group.Incr(query.Get("key"), delta, ttl)
+		if errors.Is(err, cache.ErrNotInteger) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(strconv.AppendInt(nil, n, 10))
+	})
+	return mux
+}
+
+func main() {
+	var path string
+	flag.StringVar(&path, "config", "cached.json", "config file")
+	flag.Parse()
+
+	conf, err := loadConfig(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool, err := newPool(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, gc := range conf.Groups {
+		g, err := cache.NewGroup(gc.Name, gc.CacheBytes, newGetter(gc.Source))
+		if err != nil {
+			log.Fatalf("group %s: %v", gc.Name, err)
+		}
+		g.RegisterPeers(pool)
+		if l := gc.Limits; l != nil {
+			g.SetLoadLimits(cache.LoadLimits{
+				MaxConcurrent: l.MaxConcurrent,
+				MaxQueue:      l.MaxQueue,
+				MaxWait:       time.Duration(l.MaxWait),
+				Rate:          l.Rate,
+				Burst:         l.Burst,
+			})
+		}
+		if h := gc.Hedge; h != nil {
+			g.SetHedging(&cache.HedgeOptions{
+				Percentile: h.Percentile,
+				MinDelay:   time.Duration(h.MinDelay),
+				MaxDelay:   time.Duration(h.MaxDelay),
+				AllowLocal: h.AllowLocal,
+			})
+		}
+		if hk := gc.HotKeys; hk != nil {
+			g.SetHotKeys(&cache.HotKeyOptions{
+				K:         hk.K,
+				HalfLife:  time.Duration(hk.HalfLife),
+				Threshold: hk.Threshold,
+				Mirror:    hk.Mirror,
+				MirrorTTL: time.Duration(hk.MirrorTTL),
+			})
+		}
+		if s := gc.Stale; s != nil {
+			g.SetStaleOnError(&cache.StaleOptions{MaxBytes: s.MaxBytes, Grace: time.Duration(s.Grace)})
+		}
+		if d := gc.Disk; d != nil {
+			err = g.EnableDiskTier(d.Dir, disk.Options{MaxBytes: d.MaxBytes, SegmentBytes: d.SegmentBytes})
+			if err != nil {
+				log.Fatalf("group %s: %v", gc.Name, err)
+			}
+		}
+		g.SetCacheBounds(gc.MinBytes, gc.MaxBytes)
+		if wb := gc.WriteBehind; wb != nil {
+			g.SetWriteBehind(&cache.WriteBehindOptions{
+				BatchSize:     wb.BatchSize,
+				FlushInterval: time.Duration(wb.FlushInterval),
+				MaxPending:    wb.MaxPending,
+				MaxRetries:    wb.MaxRetries,
+				RetryBackoff:  time.Duration(wb.RetryBackoff),
+			})
+		}
+	}
+	if mb := conf.MemoryBudget; mb != nil {
+		cache.DefaultRegistry.SetMemoryBudget(&cache.MemoryBudget{
+			TotalBytes:    mb.TotalBytes,
+			HeapSoftLimit: mb.HeapSoftLimit,
+			Interval:      time.Duration(mb.Interval),
+		})
+	}
+
+	peerServer := &http.Server{Addr: conf.Listen, Handler: pool, TLSConfig: pool.ServerTLSConfig()}
+	servers := []*http.Server{peerServer}
+	if conf.API != "" {
+		servers = append(servers, &http.Server{Addr: conf.API, Handler: apiHandler()})
+	}
+	if conf.Admin != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/_cache_admin/", cache.NewAdmin())
+		mux.Handle("/metrics", cache.NewMetrics(pool))
+		mux.Handle("/_cache_ring", pool.RingHandler())
+		servers = append(servers, &http.Server{Addr: conf.Admin, Handler: mux})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	errc := make(chan error, len(servers)+2)
+	var redis *cache.RedisServer
+	if conf.Redis != "" {
+		redis = cache.NewRedisServer()
+		go func() {
+			log.Println("cached redis is listening at", conf.Redis)
+			errc <- redis.ListenAndServe(conf.Redis)
+		}()
+	}
+	var memcache *cache.MemcacheServer
+	if conf.Memcache != "" {
+		memcache = cache.NewMemcacheServer()
+		go func() {
+			log.Println("cached memcache is listening at", conf.Memcache)
+			errc <- memcache.ListenAndServe(conf.Memcache)
+		}()
+	}
+	for _, s := range servers {
+		go func(s *http.Server) {
+			log.Println("cached is listening at", s.Addr)
+			if s.TLSConfig != nil {
+				errc <- s.ListenAndServeTLS("", "")
+			} else {
+				errc <- s.ListenAndServe()
+			}
+		}(s)
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Println("shutting down")
+	case err := <-errc:
+		log.Println("server stopped:", err)
+	}
+
+	if redis != nil {
+		redis.Close()
+	}
+	if memcache != nil {
+		memcache.Close()
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(conf.ShutdownTimeout))
+	defer cancel()
+	// 先通知其他节点不再转发请求过来，等待正在处理的请求结束
+	if err := pool.Shutdown(shutdownCtx); err != nil {
+		log.Println("shutdown pool:", err)
+	}
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *http.Server) {
+			defer wg.Done()
+			if err := s.Shutdown(shutdownCtx); err != nil {
+				log.Printf("shutdown %s: %v", s.Addr, err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	// 提交 write-behind 队列中剩余的写入
+	for _, g := range cache.DefaultRegistry.Groups() {
+		g.SetWriteBehind(nil)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cache"
+)
+
+const defaultSourceTimeout = 10 * time.Second
+
+func newGetter(c SourceConfig) cache.Getter {
+	if c.Type == "file" {
+		if c.Writable {
+			return writableDir(c.Path)
+		}
+		return fileGetter(c.Path)
+	}
+	timeout := time.Duration(c.Timeout)
+	if timeout <= 0 {
+		timeout = defaultSourceTimeout
+	}
+	return &httpGetter{baseURL: c.URL, client: &http.Client{Timeout: timeout}}
+}
+
+// fileGetter 从目录中读取与 key 同名的文件，文件不存在时返回 cache.ErrNotFound
+func fileGetter(dir string) cache.GetterFunc {
+	return func(key string) ([]byte, error) {
+		b, err := ioutil.ReadFile(keyPath(dir, key))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", key, cache.ErrNotFound)
+		}
+		return b, err
+	}
+}
+
+// keyPath 返回 key 对应的文件，防止 ../ 访问到目录以外的文件
+func keyPath(dir, key string) string {
+	return filepath.Join(dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+// writableDir 在 fileGetter 的基础上实现 cache.Setter 和 cache.Deleter
+type writableDir string
+
+func (d writableDir) Get(key string) ([]byte, error) {
+	return fileGetter(string(d))(key)
+}
+
+func (d writableDir) Set(key string, value []byte) error {
+	name := keyPath(string(d), key)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, value, 0644)
+}
+
+func (d writableDir) Delete(key string) error {
+	err := os.Remove(keyPath(string(d), key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// httpGetter 从上游 http 服务中读取 baseURL + key，上游返回 404 时返回 cache.ErrNotFound
+type httpGetter struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (h *httpGetter) Get(key string) ([]byte, error) {
+	u := h.baseURL
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	res, err := h.client.Get(u + url.PathEscape(key))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", key, cache.ErrNotFound)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned: %v", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
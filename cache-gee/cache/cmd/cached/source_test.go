@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cache"
+	"cache/resp"
+)
+
+// 数据源中不存在的 key 通过 redis 协议读取时是 nil，而不是错误
+func TestSourceNotFound(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "Tom"), []byte("630"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	upstream := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer upstream.Close()
+
+	registry := cache.NewRegistry()
+	if _, err := registry.NewGroup("file", 2<<10, newGetter(SourceConfig{Type: "file", Path: dir})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.NewGroup("http", 2<<10, newGetter(SourceConfig{Type: "http", URL: upstream.URL})); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := cache.NewRedisServer()
+	server.Registry = registry
+	go server.Serve(l)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r, w := resp.NewReader(conn), resp.NewWriter(conn)
+	do := func(args ...string) interface{} {
+		w.WriteCommand(args...)
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		v, err := r.ReadReply()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for _, group := range []string{"file", "http"} {
+		if v := do("GET", group+":nobody"); v != nil {
+			t.Fatalf("%s: expect nil for missing key, got %#v", group, v)
+		}
+		expect := []interface{}{[]byte("630"), nil}
+		if v := do("MGET", group+":Tom", group+":nobody"); !reflect.DeepEqual(v, expect) {
+			t.Fatalf("%s: expect %q, got %#v", group, expect, v)
+		}
+	}
+}
@@ -53,7 +53,103 @@ func (m *Map) Get(key string) string {
 	}
 	// get hash
 	hash := int(m.hash([]byte(key)))
+	return m.owner(hash)
+}
+
+// GetN 从 key 所在的位置开始顺时针查找，返回至多 n 个不同的真实节点，第一个就是 Get 的结果
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+
+	var nodes []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Len 返回哈希环上虚拟节点的数量
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+// hashSpace 哈希环的大小，Hash 返回 uint32
+const hashSpace = 1 << 32
+
+// Node 真实节点在哈希环上的分布
+type Node struct {
+	Name   string   `json:"name"`
+	VNodes []uint32 `json:"vnodes"` // 虚拟节点的位置，从小到大
+	Share  float64  `json:"share"`  // 负责的哈希空间的比例
+}
+
+// Nodes 返回按名字排序的真实节点以及它们的虚拟节点和负责的哈希空间
+func (m *Map) Nodes() []Node {
+	index := make(map[string]int)
+	var nodes []Node
+	for i, hash := range m.keys {
+		name := m.hashMap[hash]
+		idx, ok := index[name]
+		if !ok {
+			idx = len(nodes)
+			index[name] = idx
+			nodes = append(nodes, Node{Name: name})
+		}
+		nodes[idx].VNodes = append(nodes[idx].VNodes, uint32(hash))
+		// 虚拟节点负责 (上一个虚拟节点, hash] 这一段，第一个虚拟节点还负责环尾部的一段
+		// 哈希冲突时重复的位置长度为 0
+		nodes[idx].Share += float64(m.span(i)) / hashSpace
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes
+}
+
+// span 返回第 i 个虚拟节点负责的哈希空间大小
+func (m *Map) span(i int) int64 {
+	if i == 0 {
+		return int64(m.keys[0]) + hashSpace - int64(m.keys[len(m.keys)-1])
+	}
+	return int64(m.keys[i]) - int64(m.keys[i-1])
+}
+
+// Moved 返回从 m 切换到 other 时改变了所属节点的哈希空间比例，
+// 也就是 key 均匀分布时需要迁移的 key 的比例
+func (m *Map) Moved(other *Map) float64 {
+	if len(m.keys) == 0 || len(other.keys) == 0 {
+		if len(m.keys) == len(other.keys) {
+			return 0
+		}
+		return 1
+	}
+	// 两个环的虚拟节点把哈希空间切分成若干段，每一段在两个环上都只属于一个节点
+	bounds := make([]int, 0, len(m.keys)+len(other.keys))
+	bounds = append(bounds, m.keys...)
+	bounds = append(bounds, other.keys...)
+	sort.Ints(bounds)
+
+	var moved int64
+	prev := bounds[len(bounds)-1] - hashSpace
+	for _, b := range bounds {
+		if b > prev && m.owner(b) != other.owner(b) {
+			moved += int64(b - prev)
+		}
+		prev = b
+	}
+	return float64(moved) / hashSpace
+}
 
+// owner 返回哈希值对应的真实节点
+func (m *Map) owner(hash int) string {
 	idx := sort.Search(len(m.keys), func(i int) bool {
 		return m.keys[i] >= hash
 	})
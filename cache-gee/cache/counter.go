@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pb "cache/cachepb"
+)
+
+// ErrNotInteger Incr、Decr 时已有的值不是十进制整数，或者结果超出 int64 的范围
+var ErrNotInteger = errors.New("value is not an integer or out of range")
+
+// Incr 把 key 对应的计数器加上 delta，返回修改之后的值，在 PickPeer 选出的 owner 上原子地执行。
+// 计数器以十进制字符串保存在同一个 LRU 中，Get 返回的也是这个字符串。
+// 没有缓存的 key 从 0 开始，不会调用 Getter，也不会写入数据源；
+// ttl 大于 0 时只在创建计数器时设置过期时间，之后的修改保留原来的过期时间
+func (g *Group) Incr(key string, delta int64, ttl time.Duration) (int64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			return g.incrOnPeer(peer, key, delta, ttl)
+		}
+	}
+	return g.incrLocally(key, delta, ttl)
+}
+
+// Decr 等同于 Incr(key, -delta, ttl)
+func (g *Group) Decr(key string, delta int64, ttl time.Duration) (int64, error) {
+	return g.Incr(key, -delta, ttl)
+}
+
+func (g *Group) incrOnPeer(peer PeerGetter, key string, delta int64, ttl time.Duration) (int64, error) {
+	cp, ok := peer.(CounterPeerGetter)
+	if !ok {
+		return 0, fmt.Errorf("peer %s does not support counters", peerName(peer))
+	}
+	out := &pb.IncrResponse{}
+	err := cp.Incr(&pb.IncrRequest{Group: g.name, Key: key, Delta: delta, Ttl: int64(ttl)}, out)
+	if err != nil {
+		return 0, err
+	}
+	if out.GetNotInteger() {
+		return 0, ErrNotInteger
+	}
+	return out.GetValue(), nil
+}
+
+func (g *Group) incrLocally(key string, delta int64, ttl time.Duration) (int64, error) {
+	gen := g.Generation()
+	// 磁盘上的值先移回内存
+	g.mainCache.get(key, gen)
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	n, ok := g.mainCache.incr(key, delta, expire, gen)
+	if !ok {
+		return 0, ErrNotInteger
+	}
+	g.Stats.Incrs.Add(1)
+	return n, nil
+}
@@ -0,0 +1,521 @@
+// Package disk 把缓存值保存在本地磁盘上只追加写入的 segment 文件中，内存中只保存索引
+package disk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxBytes     = 1 << 30
+	defaultSegmentBytes = 16 << 20
+	defaultCompactRatio = 0.5
+
+	segmentExt = ".seg"
+	// headerSize crc32 | flags | key 长度 | value 长度，crc32 覆盖之后的所有内容
+	headerSize    = 13
+	flagTombstone = 1
+)
+
+var errCorrupt = errors.New("corrupt record")
+
+// Options Store 的配置
+type Options struct {
+	MaxBytes     int64   // 所有 segment 文件的大小上限，超出时丢弃最旧的 segment，默认 1GB
+	SegmentBytes int64   // 单个 segment 文件的大小，默认 16MB，不超过 MaxBytes 的一半
+	CompactRatio float64 // 有效数据低于这个比例的 segment 会被重写，默认 0.5
+}
+
+// Stats Store 的使用情况
+type Stats struct {
+	Keys        int   `json:"keys"`
+	Segments    int   `json:"segments"`
+	FileBytes   int64 `json:"fileBytes"`   // segment 文件的总大小
+	LiveBytes   int64 `json:"liveBytes"`   // 其中仍然有效的记录
+	Puts        int64 `json:"puts"`        // 写入的次数
+	Hits        int64 `json:"hits"`        // Get 命中的次数
+	Evictions   int64 `json:"evictions"`   // 因为 MaxBytes 被丢弃的 key
+	Compactions int64 `json:"compactions"` // 被重写的 segment
+}
+
+type segment struct {
+	id   int
+	f    *os.File
+	size int64
+	live int64 // 索引中仍然指向这个 segment 的记录的大小
+}
+
+// location 记录在 segment 中的位置
+type location struct {
+	seg       *segment
+	off       int64
+	size      int64
+	seq       uint64 // 每次写入递增，用于 DeleteIf
+	tombstone bool   // 删除标记，更旧的 segment 中可能还有这个 key，重启时需要它覆盖掉
+}
+
+// Store 并发安全的磁盘缓存
+// 写入只追加到最新的 segment，覆盖和删除留下的无效记录在压缩时清理
+type Store struct {
+	// OnEvict 因为 MaxBytes 被丢弃的 key，在 Put 中同步调用，调用时持有 Store 的锁，不能调用 Store 的方法
+	OnEvict func(key string)
+
+	mtx      sync.Mutex
+	dir      string
+	opts     Options
+	segments []*segment // 从旧到新，最后一个正在写入
+	lastID   int        // 最后打开的 segment 的 id，新的 segment 不会重新使用删除失败的文件
+	index    map[string]location
+	keys     int // index 中不是删除标记的 key
+	seq      uint64
+	stats    Stats
+}
+
+// Open 打开 dir 中的 segment 文件并重建索引，目录不存在时创建
+// 文件末尾不完整或者校验失败的记录会被截断
+func Open(dir string, opts Options) (*Store, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxBytes
+	}
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = defaultSegmentBytes
+	}
+	if opts.SegmentBytes > opts.MaxBytes/2 {
+		opts.SegmentBytes = opts.MaxBytes / 2
+	}
+	if opts.CompactRatio <= 0 || opts.CompactRatio >= 1 {
+		opts.CompactRatio = defaultCompactRatio
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &Store{dir: dir, opts: opts, index: make(map[string]location)}
+	ids, err := segmentIDs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		seg, err := s.openSegment(id)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.segments = append(s.segments, seg)
+		if err = s.replay(seg); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	if len(s.segments) == 0 {
+		if err = s.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func segmentIDs(dir string) ([]int, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		if id, err := strconv.Atoi(strings.TrimSuffix(name, segmentExt)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (s *Store) segmentPath(id int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%08d%s", id, segmentExt))
+}
+
+func (s *Store) openSegment(id int) (*segment, error) {
+	f, err := os.OpenFile(s.segmentPath(id), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if id > s.lastID {
+		s.lastID = id
+	}
+	return &segment{id: id, f: f, size: info.Size()}, nil
+}
+
+// replay 读取 segment 中的所有记录，更新索引
+func (s *Store) replay(seg *segment) error {
+	r := bufio.NewReader(io.NewSectionReader(seg.f, 0, seg.size))
+	var off int64
+	for off < seg.size {
+		key, size, flags, err := readRecord(r, seg.size-off)
+		if err != nil {
+			log.Printf("[disk] %s: truncating at %d: %v", seg.f.Name(), off, err)
+			seg.size = off
+			return seg.f.Truncate(off)
+		}
+		s.seq++
+		loc := location{seg: seg, off: off, size: size, seq: s.seq, tombstone: flags&flagTombstone != 0}
+		// 删除标记只有在覆盖之前的记录时才需要
+		if old, ok := s.index[key]; !loc.tombstone || (ok && !old.tombstone) {
+			s.apply(key, loc)
+		}
+		off += size
+	}
+	return nil
+}
+
+// readRecord 从 r 中读取一条记录并校验，返回 key 和整条记录的大小
+// remain 是 segment 中剩余的大小，header 中的长度超出时当作损坏，不会按照它分配内存
+func readRecord(r io.Reader, remain int64) (key string, size int64, flags byte, err error) {
+	var header [headerSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return "", 0, 0, err
+	}
+	klen := binary.BigEndian.Uint32(header[5:9])
+	vlen := binary.BigEndian.Uint32(header[9:13])
+	if int64(klen)+int64(vlen) > remain-headerSize {
+		return "", 0, 0, errCorrupt
+	}
+	body := make([]byte, int(klen)+int(vlen))
+	if _, err = io.ReadFull(r, body); err != nil {
+		return "", 0, 0, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(header[:4]) {
+		return "", 0, 0, errCorrupt
+	}
+	return string(body[:klen]), int64(headerSize + len(body)), header[4], nil
+}
+
+func encodeRecord(key string, value []byte, flags byte) []byte {
+	rec := make([]byte, headerSize+len(key)+len(value))
+	rec[4] = flags
+	binary.BigEndian.PutUint32(rec[5:9], uint32(len(key)))
+	binary.BigEndian.PutUint32(rec[9:13], uint32(len(value)))
+	copy(rec[headerSize:], key)
+	copy(rec[headerSize+len(key):], value)
+	binary.BigEndian.PutUint32(rec[:4], crc32.ChecksumIEEE(rec[4:]))
+	return rec
+}
+
+// apply 更新索引，之前的记录变为无效
+func (s *Store) apply(key string, loc location) {
+	if _, ok := s.index[key]; ok {
+		s.forget(key)
+	}
+	s.index[key] = loc
+	loc.seg.live += loc.size
+	if !loc.tombstone {
+		s.keys++
+	}
+}
+
+// forget 从索引中删除 key
+func (s *Store) forget(key string) {
+	old := s.index[key]
+	old.seg.live -= old.size
+	if !old.tombstone {
+		s.keys--
+	}
+	delete(s.index, key)
+}
+
+// rotate 创建新的 segment 用于写入
+func (s *Store) rotate() error {
+	seg, err := s.openSegment(s.lastID + 1)
+	if err != nil {
+		return err
+	}
+	s.segments = append(s.segments, seg)
+	return nil
+}
+
+// append 把记录写入最新的 segment，写满或者之前创建失败时先创建新的 segment
+func (s *Store) append(rec []byte) (location, error) {
+	if len(s.segments) == 0 {
+		if err := s.rotate(); err != nil {
+			return location{}, err
+		}
+	}
+	active := s.segments[len(s.segments)-1]
+	if active.size > 0 && active.size+int64(len(rec)) > s.opts.SegmentBytes {
+		if err := s.rotate(); err != nil {
+			return location{}, err
+		}
+		active = s.segments[len(s.segments)-1]
+	}
+	if _, err := active.f.WriteAt(rec, active.size); err != nil {
+		return location{}, err
+	}
+	loc := location{seg: active, off: active.size, size: int64(len(rec))}
+	active.size += loc.size
+	s.seq++
+	loc.seq = s.seq
+	return loc, nil
+}
+
+// Put 写入 key，覆盖之前的值
+// 压缩以及超出 MaxBytes 时的丢弃只在 Put 中进行
+func (s *Store) Put(key string, value []byte) error {
+	_, err := s.PutSeq(key, value)
+	return err
+}
+
+// PutSeq 与 Put 相同，同时返回写入的序号，用于 DeleteIf
+func (s *Store) PutSeq(key string, value []byte) (uint64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	loc, err := s.append(encodeRecord(key, value, 0))
+	if err != nil {
+		return 0, err
+	}
+	s.apply(key, loc)
+	s.stats.Puts++
+	return loc.seq, s.maintain()
+}
+
+// Get 返回 key 的值以及写入时的序号，序号用于 DeleteIf
+// 读取失败时仍然返回序号，调用方可以删除损坏的记录
+func (s *Store) Get(key string) (value []byte, seq uint64, ok bool, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	loc, ok := s.index[key]
+	if !ok || loc.tombstone {
+		return nil, 0, false, nil
+	}
+	rec := make([]byte, loc.size)
+	if _, err = loc.seg.f.ReadAt(rec, loc.off); err != nil {
+		return nil, loc.seq, false, err
+	}
+	if crc32.ChecksumIEEE(rec[4:]) != binary.BigEndian.Uint32(rec[:4]) {
+		return nil, loc.seq, false, fmt.Errorf("%s: %w", key, errCorrupt)
+	}
+	s.stats.Hits++
+	klen := binary.BigEndian.Uint32(rec[5:9])
+	return rec[headerSize+int(klen):], loc.seq, true, nil
+}
+
+// Has key 是否存在
+func (s *Store) Has(key string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	loc, ok := s.index[key]
+	return ok && !loc.tombstone
+}
+
+// Delete 删除 key，key 不存在时什么都不做
+// 只写入一条很小的删除标记，不会压缩或者丢弃 segment
+func (s *Store) Delete(key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.delete(key)
+}
+
+// DeleteIf 只有 key 在 Get 之后没有被修改时才删除，返回是否删除
+func (s *Store) DeleteIf(key string, seq uint64) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if loc, ok := s.index[key]; !ok || loc.tombstone || loc.seq != seq {
+		return false, nil
+	}
+	return true, s.delete(key)
+}
+
+func (s *Store) delete(key string) error {
+	if loc, ok := s.index[key]; !ok || loc.tombstone {
+		return nil
+	}
+	loc, err := s.append(encodeRecord(key, nil, flagTombstone))
+	if err != nil {
+		return err
+	}
+	loc.tombstone = true
+	s.apply(key, loc)
+	return nil
+}
+
+// Scan 对每一个 key 调用 fn，顺序不确定，fn 中不能调用 Store 的方法
+func (s *Store) Scan(fn func(key string, value []byte)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for key, loc := range s.index {
+		if loc.tombstone {
+			continue
+		}
+		rec := make([]byte, loc.size)
+		if _, err := loc.seg.f.ReadAt(rec, loc.off); err != nil {
+			return err
+		}
+		fn(key, rec[headerSize+len(key):])
+	}
+	return nil
+}
+
+// maintain 压缩有效数据过少的 segment，超出 MaxBytes 时丢弃最旧的 segment
+func (s *Store) maintain() error {
+	// 每次最多压缩一个，避免一次写入等待太久
+	for _, seg := range s.segments[:len(s.segments)-1] {
+		if float64(seg.live) < float64(seg.size)*s.opts.CompactRatio {
+			if err := s.compact(seg); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	for len(s.segments) > 1 && s.fileBytes() > s.opts.MaxBytes {
+		if err := s.drop(s.segments[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) fileBytes() int64 {
+	var n int64
+	for _, seg := range s.segments {
+		n += seg.size
+	}
+	return n
+}
+
+// locations 返回索引中位于 seg 的记录，按照偏移排序
+func (s *Store) locations(seg *segment) []string {
+	var keys []string
+	for key, loc := range s.index {
+		if loc.seg == seg {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return s.index[keys[i]].off < s.index[keys[j]].off })
+	return keys
+}
+
+// compact 把 seg 中仍然有效的记录重新写入最新的 segment，然后删除 seg
+func (s *Store) compact(seg *segment) error {
+	oldest := seg == s.segments[0]
+	for _, key := range s.locations(seg) {
+		loc := s.index[key]
+		if loc.tombstone && oldest {
+			// 没有更旧的 segment，删除标记不再需要
+			s.forget(key)
+			continue
+		}
+		rec := make([]byte, loc.size)
+		if _, err := seg.f.ReadAt(rec, loc.off); err != nil {
+			return err
+		}
+		next, err := s.append(rec)
+		if err != nil {
+			return err
+		}
+		next.tombstone = loc.tombstone
+		s.apply(key, next)
+	}
+	s.stats.Compactions++
+	return s.remove(seg)
+}
+
+// drop 丢弃 seg 以及其中的所有 key
+func (s *Store) drop(seg *segment) error {
+	for _, key := range s.locations(seg) {
+		loc := s.index[key]
+		s.forget(key)
+		if !loc.tombstone {
+			s.stats.Evictions++
+			if s.OnEvict != nil {
+				s.OnEvict(key)
+			}
+		}
+	}
+	return s.remove(seg)
+}
+
+func (s *Store) remove(seg *segment) error {
+	for i, other := range s.segments {
+		if other == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+	seg.f.Close()
+	if len(s.segments) == 0 {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	return os.Remove(seg.f.Name())
+}
+
+// Clear 删除所有的 key 以及 segment 文件，部分文件删除失败时返回第一个错误
+func (s *Store) Clear() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	segments := s.segments
+	s.segments = nil
+	s.index = make(map[string]location)
+	s.keys = 0
+	var first error
+	for _, seg := range segments {
+		seg.f.Close()
+		if err := os.Remove(seg.f.Name()); err != nil && first == nil {
+			first = err
+		}
+	}
+	if err := s.rotate(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
+
+// Stats 返回 Store 的使用情况
+func (s *Store) Stats() Stats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st := s.stats
+	st.Keys = s.keys
+	st.Segments = len(s.segments)
+	for _, seg := range s.segments {
+		st.FileBytes += seg.size
+		st.LiveBytes += seg.live
+	}
+	return st
+}
+
+// Close 关闭所有的 segment 文件，之后不能再使用
+func (s *Store) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var err error
+	for _, seg := range s.segments {
+		if e := seg.f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
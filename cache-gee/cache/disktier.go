@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cache/disk"
+)
+
+const (
+	diskMetaFile = "tier.meta"
+	// diskFormat encodeDiskValue 的格式，修改格式时加一，格式不一致的目录在开启时被清空
+	diskFormat = 2
+)
+
+var errBadDiskValue = errors.New("bad disk value")
+
+// EnableDiskTier 在 dir 中开启本地磁盘上的二级缓存。
+// 因为 cacheBytes 被淘汰的缓存值写入磁盘，之后命中时再移回内存，同一个 key 只会在其中一层。
+// dir 中已经存在的数据会被加载，重启之后仍然可以使用；Remove、InvalidateTag 同时作用于磁盘。
+// group 的 generation 同样保存在 dir 中，重启之后 BumpGeneration 之前写入的值不会再返回。
+// 调用多次时 panic
+func (g *Group) EnableDiskTier(dir string, opts disk.Options) error {
+	if g.diskMeta.Load() != nil {
+		panic("EnableDiskTier called more than once")
+	}
+	store, err := disk.Open(dir, opts)
+	if err != nil {
+		return err
+	}
+	meta := &diskMeta{path: filepath.Join(dir, diskMetaFile), gen: -1}
+	format, gen, err := readDiskMeta(meta.path)
+	switch {
+	case err == nil && format == diskFormat:
+		g.observeGeneration(gen)
+	case err == nil || os.IsNotExist(err):
+		// 没有记录 generation 或者格式不一致，已有的数据无法判断是否仍然有效
+		err = store.Clear()
+	}
+	if err != nil {
+		store.Close()
+		return err
+	}
+	ok, err := g.mainCache.enableDisk(store)
+	if !ok {
+		store.Close()
+		panic("EnableDiskTier called more than once")
+	}
+	g.diskMeta.Store(meta)
+	g.saveGeneration(g.Generation())
+	return err
+}
+
+// diskMeta 磁盘目录中记录的格式以及 generation
+type diskMeta struct {
+	path string
+	mtx  sync.Mutex
+	gen  int64 // 已经写入的 generation
+}
+
+// save 写入更大的 generation，先写入临时文件再 rename，不会留下写了一半的文件
+func (m *diskMeta) save(gen int64) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if gen <= m.gen {
+		return nil
+	}
+	tmp := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", diskFormat, gen)), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return err
+	}
+	m.gen = gen
+	return nil
+}
+
+func readDiskMeta(path string) (format int, gen int64, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err = fmt.Sscanf(string(b), "%d %d", &format, &gen); err != nil {
+		// 无法解析时当作格式不一致
+		return 0, 0, nil
+	}
+	return format, gen, nil
+}
+
+// saveGeneration 开启磁盘缓存时把 generation 写入磁盘目录
+func (g *Group) saveGeneration(gen int64) {
+	if m, _ := g.diskMeta.Load().(*diskMeta); m != nil {
+		if err := m.save(gen); err != nil {
+			log.Printf("[GeeCache] save generation of %s: %v", g.name, err)
+		}
+	}
+}
+
+// DiskStats 返回磁盘缓存的使用情况，没有开启时返回零值
+func (g *Group) DiskStats() disk.Stats {
+	return g.mainCache.diskStats()
+}
+
+// encodeDiskValue 磁盘上的格式：expire | gen | tags | extra | 缓存值
+func encodeDiskValue(v ByteView, tags []string) []byte {
+	var n [binary.MaxVarintLen64]byte
+	b := make([]byte, 0, 32+v.Len())
+	b = append(b, n[:binary.PutVarint(n[:], unixNano(v.e))]...)
+	b = append(b, n[:binary.PutVarint(n[:], v.gen)]...)
+	b = append(b, n[:binary.PutUvarint(n[:], uint64(len(tags)))]...)
+	for _, tag := range tags {
+		b = append(b, n[:binary.PutUvarint(n[:], uint64(len(tag)))]...)
+		b = append(b, tag...)
+	}
+	x := v.x
+	if x == nil {
+		x = &extra{}
+	}
+	b = appendExtra(b, x)
+	buf := bytes.NewBuffer(b)
+	v.WriteTo(buf)
+	return buf.Bytes()
+}
+
+func decodeDiskValue(b []byte) (ByteView, []string, error) {
+	expire, m := binary.Varint(b)
+	if m <= 0 {
+		return ByteView{}, nil, errBadDiskValue
+	}
+	b = b[m:]
+	gen, m := binary.Varint(b)
+	if m <= 0 {
+		return ByteView{}, nil, errBadDiskValue
+	}
+	b = b[m:]
+	count, m := binary.Uvarint(b)
+	if m <= 0 || count > uint64(len(b)) {
+		return ByteView{}, nil, errBadDiskValue
+	}
+	b = b[m:]
+	var tags []string
+	for i := uint64(0); i < count; i++ {
+		n, m := binary.Uvarint(b)
+		if m <= 0 || uint64(len(b)-m) < n {
+			return ByteView{}, nil, errBadDiskValue
+		}
+		tags = append(tags, string(b[m:m+int(n)]))
+		b = b[m+int(n):]
+	}
+	x, rest, ok := readExtra(b)
+	if !ok {
+		return ByteView{}, nil, errBadDiskValue
+	}
+	v := newByteView(rest, fromUnixNano(expire), gen)
+	if *x != (extra{}) {
+		v.x = x
+	}
+	return v, tags, nil
+}
@@ -1,9 +1,15 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	pb "cache/cachepb"
 	"cache/singleflight"
@@ -15,6 +21,9 @@ type Getter interface {
 	Get(key string) ([]byte, error)
 }
 
+// ErrNotFound Getter 可以返回(或包装)这个错误表示数据源中不存在 key
+var ErrNotFound = errors.New("not found")
+
 // GetterFunc impl Getter
 type GetterFunc func(key string) ([]byte, error)
 
@@ -23,6 +32,11 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// TagGetter 可选接口，Getter 实现它时加载的值会带上 tag，用于 Group.InvalidateTag
+type TagGetter interface {
+	GetTags(key string) (value []byte, tags []string, err error)
+}
+
 // Group 可以看成是一个命名空间
 // eg:和用户相关的就保存在name=user的cache中，和密码有关的就保存在name=password的cache中
 type Group struct {
@@ -33,22 +47,62 @@ type Group struct {
 	// use singleflight.Group to make sure that
 	// each key is only fetched once
 	loader *singleflight.Group
+
+	// Stats are statistics on the group.
+	Stats Stats
+
+	loadLatency histogram // Getter 的耗时
+	peerLatency histogram // 从其他节点获取的耗时
+
+	observer Observer // 为 nil 时不产生任何开销
+
+	limiter atomic.Value // *loadLimiter，未设置时不限制
+
+	// generation 小于它的缓存值都被当作已经失效，通过 BumpGeneration 修改
+	generation int64
+
+	hedger atomic.Value // *hedger，为 nil 时不发送 hedged request
+	// localLoader 处理 hedged request，与 loader 分开，避免等待正在向 owner 发送的请求
+	localLoader singleflight.Group
+
+	writeMtx    sync.Mutex
+	writeBehind *writeBehind // 为 nil 时是 write-through
+
+	bounds atomic.Value // cacheBounds，MemoryBudget 分配时使用
+
+	hotKeys atomic.Value // *hotKeys，为 nil 时不统计
+
+	diskMeta atomic.Value // *diskMeta，开启磁盘缓存之后记录 generation
+
+	casLocks keyLocks // 按 key 串行执行 CompareAndSet
 }
 
-var (
-	mtx    sync.RWMutex              // 读写锁
-	groups = make(map[string]*Group) // key:groupName,value:Group
-)
+// NewGroup 在 DefaultRegistry 中创建一个新的Group，同名的 group 已经存在时返回 ErrGroupExists
+func NewGroup(name string, cacheBytes int64, getter Getter) (*Group, error) {
+	return DefaultRegistry.NewGroup(name, cacheBytes, getter)
+}
+
+// ReplaceGroup 在 DefaultRegistry 中创建一个新的Group，替换掉同名的 group
+func ReplaceGroup(name string, cacheBytes int64, getter Getter) *Group {
+	return DefaultRegistry.ReplaceGroup(name, cacheBytes, getter)
+}
+
+// GetGroup returns the named group previously created with NewGroup, or
+// nil if there's no such group.
+func GetGroup(name string) *Group {
+	return DefaultRegistry.GetGroup(name)
+}
 
-// NewGroup 返回一个新的Group
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+// DeleteGroup 从 DefaultRegistry 中删除 group，并清空它的缓存
+func DeleteGroup(name string) bool {
+	return DefaultRegistry.DeleteGroup(name)
+}
+
+func newGroup(name string, cacheBytes int64, getter Getter) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
-	mtx.Lock()
-	defer mtx.Unlock()
-
-	g := &Group{
+	return &Group{
 		name:   name,
 		getter: getter,
 		mainCache: cache{
@@ -56,18 +110,16 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 		},
 		loader: &singleflight.Group{},
 	}
+}
 
-	groups[name] = g
-	return g
+// Name 返回 group 的名字
+func (g *Group) Name() string {
+	return g.name
 }
 
-// GetGroup returns the named group previously created with NewGroup, or
-// nil if there's no such group.
-func GetGroup(name string) *Group {
-	mtx.RLock()
-	group := groups[name]
-	mtx.RUnlock()
-	return group
+// SetCacheBytes 修改 group 允许使用的最大内存，超出的部分会立即被淘汰
+func (g *Group) SetCacheBytes(cacheBytes int64) {
+	g.mainCache.resize(cacheBytes)
 }
 
 // Get 根据key返回存储的数据
@@ -75,15 +127,276 @@ func (g *Group) Get(key string) (ByteView, error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
+	g.Stats.Gets.Add(1)
+	g.recordGet(key)
 
 	// 存在cache
-	if v, ok := g.mainCache.get(key); ok {
-		log.Printf("[Cache Hit]  key : %v\n", key)
+	if v, ok := g.lookupCache(key); ok {
 		return v, nil
 	}
+	return g.load(key)
+}
+
+// GetStream 与 Get 相同，但是以 io.ReadCloser 的方式返回缓存值，调用方读取完毕之后需要 Close
+// key 属于其他节点时直接转发对方的响应，大的缓存值不会完整地读取到内存中
+func (g *Group) GetStream(key string) (*Stream, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	g.Stats.Gets.Add(1)
+	g.recordGet(key)
+
+	if v, ok := g.lookupCache(key); ok {
+		return viewStream(v), nil
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			if sp, ok := peer.(StreamPeerGetter); ok {
+				if g.observer != nil {
+					g.observer.OnPeerPick(g.name, key, peerName(peer))
+				}
+				body, err := g.streamFromPeer(peer, sp, key)
+				if err == nil {
+					g.Stats.PeerLoads.Add(1)
+					return body, nil
+				}
+				g.Stats.PeerErrors.Add(1)
+				log.Println("[GeeCache] Failed to stream from peer", err)
+			}
+		}
+	}
+
+	v, err := g.load(key)
+	if err != nil {
+		return nil, err
+	}
+	return viewStream(v), nil
+}
+
+func viewStream(v ByteView) *Stream {
+	return &Stream{ReadCloser: ioutil.NopCloser(v.Reader()), Meta: v.Meta(), Stale: v.stale}
+}
+
+// lookupCache 查找本地缓存并记录命中情况
+func (g *Group) lookupCache(key string) (ByteView, bool) {
+	if v, ok := g.mainCache.get(key, g.Generation()); ok {
+		log.Printf("[Cache Hit]  key : %v\n", key)
+		g.Stats.CacheHits.Add(1)
+		if g.observer != nil {
+			g.observer.OnGetHit(g.name, key)
+		}
+		return v, true
+	}
 
 	// miss cache
-	return g.load(key)
+	g.Stats.Loads.Add(1)
+	if g.observer != nil {
+		g.observer.OnGetMiss(g.name, key)
+	}
+	return ByteView{}, false
+}
+
+// Set 写入缓存，expire 为零值表示永不过期，tags 用于 InvalidateTag
+func (g *Group) Set(key string, value []byte, expire time.Time, tags ...string) error {
+	return g.SetEntry(key, Entry{Value: value, Tags: tags, Meta: Meta{Expire: expire}})
+}
+
+// SetEntry 写入带有元信息的缓存
+// 如果 key 属于其他节点，则写入到对应的节点
+// Getter 实现了 Setter 时由 owner 写入数据源，write-through 模式下写入失败时缓存不会被修改
+func (g *Group) SetEntry(key string, e Entry) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			wp, ok := peer.(WritePeerGetter)
+			if !ok {
+				return fmt.Errorf("peer %s does not support set", peerName(peer))
+			}
+			return wp.Set(&pb.SetRequest{
+				Group:           g.name,
+				Key:             key,
+				Value:           e.Value,
+				Expire:          unixNano(e.Expire),
+				Tags:            e.Tags,
+				Version:         e.Version,
+				ContentType:     e.ContentType,
+				ContentEncoding: e.ContentEncoding,
+				Flags:           e.Flags,
+			})
+		}
+	}
+	return g.setLocally(key, e)
+}
+
+// Remove 删除缓存，如果 key 属于其他节点，则从对应的节点删除，返回 key 是否存在于 owner 的缓存中(包括磁盘)
+// Getter 实现了 Deleter 时同时删除数据源中的 key。
+// 对方没有实现 RemovePeerGetter 时无法知道 key 是否存在，总是返回 true
+func (g *Group) Remove(key string) (bool, error) {
+	if key == "" {
+		return false, fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			in := &pb.Request{Group: g.name, Key: key}
+			if rp, ok := peer.(RemovePeerGetter); ok {
+				return rp.RemoveExisted(in)
+			}
+			wp, ok := peer.(WritePeerGetter)
+			if !ok {
+				return false, fmt.Errorf("peer %s does not support remove", peerName(peer))
+			}
+			return true, wp.Remove(in)
+		}
+	}
+	return g.removeLocally(key)
+}
+
+func (g *Group) setLocally(key string, e Entry) error {
+	if err := g.writeStore(Write{Key: key, Entry: e}); err != nil {
+		return err
+	}
+	g.Stats.Sets.Add(1)
+	g.populateCache(key, entryView(e, g.Generation()))
+	return nil
+}
+
+// fillLocally 只写入本地缓存，不写入数据源，已经缓存了 key 时不覆盖
+func (g *Group) fillLocally(key string, e Entry) {
+	gen := g.Generation()
+	if _, ok := g.mainCache.get(key, gen); ok {
+		return
+	}
+	g.populateCache(key, entryView(e, gen))
+}
+
+// entryView 拷贝 e 中的缓存值，并分配新的版本
+func entryView(e Entry, gen int64) ByteView {
+	v := newByteView(e.Value, time.Time{}, gen).withMeta(e.Meta)
+	v.tags = cloneTags(e.Tags)
+	v.cas = nextVersion()
+	return v
+}
+
+// InvalidateTag 删除所有节点上带有 tag 的缓存，不会修改数据源
+// 部分节点通知失败时返回错误
+func (g *Group) InvalidateTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("tag is required")
+	}
+	g.invalidateTagLocally(tag)
+	return g.broadcast(func(peer PeerGetter) error {
+		tp, ok := peer.(TagPeerGetter)
+		if !ok {
+			return errors.New("does not support tags")
+		}
+		return tp.InvalidateTag(&pb.Request{Group: g.name, Key: tag})
+	})
+}
+
+func (g *Group) invalidateTagLocally(tag string) {
+	n := g.mainCache.removeTag(tag)
+	g.Stats.Removes.Add(int64(n))
+}
+
+// cloneTags 缓存中的 tags 不能被调用方修改
+func cloneTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return append([]string(nil), tags...)
+}
+
+// Generation 返回 group 当前的 generation
+func (g *Group) Generation() int64 {
+	return atomic.LoadInt64(&g.generation)
+}
+
+// BumpGeneration 将 generation 加一并通知所有节点，之前写入的缓存全部失效
+// 部分节点通知失败时返回错误，这些节点会在之后的请求中从 cachepb.Request 得知新的 generation
+func (g *Group) BumpGeneration() error {
+	gen := atomic.AddInt64(&g.generation, 1)
+	g.saveGeneration(gen)
+	return g.broadcast(func(peer PeerGetter) error {
+		gp, ok := peer.(GenerationPeerGetter)
+		if !ok {
+			return errors.New("does not support generations")
+		}
+		return gp.SetGeneration(&pb.Request{Group: g.name, Generation: gen})
+	})
+}
+
+// broadcast 对除自己以外的所有节点调用 fn，返回失败的节点
+// PeerPicker 没有实现 PeerLister 时无法通知其他节点，返回错误
+func (g *Group) broadcast(fn func(peer PeerGetter) error) error {
+	if g.peers == nil {
+		return nil
+	}
+	lister, ok := g.peers.(PeerLister)
+	if !ok {
+		return fmt.Errorf("peer picker %T does not list peers", g.peers)
+	}
+
+	var failed []string
+	for _, peer := range lister.GetAll() {
+		if err := fn(peer); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", peerName(peer), err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to notify %d peers: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// observeGeneration 收到其他节点的 generation，只会增大
+func (g *Group) observeGeneration(gen int64) {
+	for {
+		cur := atomic.LoadInt64(&g.generation)
+		if gen <= cur {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&g.generation, cur, gen) {
+			g.saveGeneration(gen)
+			return
+		}
+	}
+}
+
+// Touch 只修改 owner 上缓存值的过期时间，返回 key 是否存在于缓存中。
+// 没有缓存的 key 不会被加载，也不会写入数据源，tag 以及元信息保持不变
+func (g *Group) Touch(key string, expire time.Time) (bool, error) {
+	if key == "" {
+		return false, fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.peers.PickPeer(key); ok {
+			tp, ok := peer.(TouchPeerGetter)
+			if !ok {
+				return false, fmt.Errorf("peer %s does not support touch", peerName(peer))
+			}
+			out := &pb.TouchResponse{}
+			err := tp.Touch(&pb.TouchRequest{Group: g.name, Key: key, Expire: unixNano(expire)}, out)
+			return out.GetFound(), err
+		}
+	}
+	return g.touchLocally(key, expire), nil
+}
+
+func (g *Group) touchLocally(key string, expire time.Time) bool {
+	gen := g.Generation()
+	// 磁盘上的值先移回内存
+	g.mainCache.get(key, gen)
+	return g.mainCache.touch(key, expire, gen)
+}
+
+func (g *Group) removeLocally(key string) (bool, error) {
+	if err := g.writeStore(Write{Key: key, Delete: true}); err != nil {
+		return false, err
+	}
+	g.Stats.Removes.Add(1)
+	return g.mainCache.remove(key), nil
 }
 
 // RegisterPeers registers a PeerPicker for choosing remote peer
@@ -94,19 +407,58 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 	g.peers = peers
 }
 
+// RegisterObserver 注册 Observer，需要在使用 group 之前调用
+func (g *Group) RegisterObserver(o Observer) {
+	if g.observer != nil {
+		panic("RegisterObserver called more than once")
+	}
+	g.observer = o
+	g.mainCache.setOnEvict(func(key string, reason EvictReason) {
+		o.OnEvict(g.name, key, reason)
+	})
+}
+
+// SetLoadLimits 限制 Getter 的调用，可以在运行时修改，超出限制时 Get 返回 ErrOverloaded
+func (g *Group) SetLoadLimits(limits LoadLimits) {
+	g.limiter.Store(newLoadLimiter(g.name, limits, &g.Stats))
+}
+
 func (g *Group) load(key string) (value ByteView, err error) {
 	// each key is only fetched once (either locally or remotely)
 	// regardless of the number of concurrent callers.
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
+	viewi, err := g.loader.Do(key, func() (v interface{}, err error) {
+		g.Stats.LoadsDeduped.Add(1)
+		if g.observer != nil {
+			g.observer.OnLoadStart(g.name, key)
+			defer func(start time.Time) {
+				g.observer.OnLoadEnd(g.name, key, time.Since(start), err)
+			}(time.Now())
+		}
 		if g.peers != nil {
 			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err := g.getFromPeer(peer, key); err == nil {
+				if g.observer != nil {
+					g.observer.OnPeerPick(g.name, key, peerName(peer))
+				}
+				value, err := g.fetchFromPeer(peer, key)
+				if err == nil {
+					g.Stats.PeerLoads.Add(1)
+					g.mirror(key, value)
 					return value, nil
 				}
+				g.Stats.PeerErrors.Add(1)
 				log.Println("[GeeCache] Failed to get from peer", err)
 			}
 		}
-		return g.getLocally(key)
+		value, err := g.getLocally(key)
+		if err != nil {
+			g.Stats.LocalLoadErrs.Add(1)
+			if v, ok := g.staleOnError(key, err); ok {
+				return v, nil
+			}
+			return nil, err
+		}
+		g.Stats.LocalLoads.Add(1)
+		return value, nil
 	})
 
 	if err == nil {
@@ -117,13 +469,26 @@ func (g *Group) load(key string) (value ByteView, err error) {
 }
 
 func (g *Group) getLocally(key string) (ByteView, error) {
+	if l, ok := g.limiter.Load().(*loadLimiter); ok {
+		release, err := l.acquire()
+		if err != nil {
+			return ByteView{}, err
+		}
+		defer release()
+	}
+
+	// 在调用 Getter 之前记录 generation，加载期间发生的 BumpGeneration 会使这次的结果失效
+	gen := g.Generation()
+
 	// 调用传入的miss cache callback
-	bytes, err := g.getter.Get(key)
+	start := time.Now()
+	e, err := g.getEntry(key)
+	g.loadLatency.since(start)
 	if err != nil {
 		return ByteView{}, err
 	}
 
-	v := ByteView{b: cloneBytes(bytes)}
+	v := entryView(e, gen)
 
 	// save cache to group
 	g.populateCache(key, v)
@@ -131,20 +496,133 @@ func (g *Group) getLocally(key string) (ByteView, error) {
 	return v, nil
 }
 
+// getEntry 按照 write-behind 队列、EntryGetter、TagGetter、Getter 的顺序加载
+func (g *Group) getEntry(key string) (e Entry, err error) {
+	if w, ok := g.pendingWrite(key); ok {
+		// 修改还没有写入数据源，不能从 Getter 读取
+		if w.Delete {
+			return Entry{}, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		return w.Entry, nil
+	}
+	switch getter := g.getter.(type) {
+	case EntryGetter:
+		return getter.GetEntry(key)
+	case TagGetter:
+		e.Value, e.Tags, err = getter.GetTags(key)
+	default:
+		e.Value, err = g.getter.Get(key)
+	}
+	return e, err
+}
+
 func (g *Group) populateCache(key string, v ByteView) {
 	g.mainCache.add(key, v)
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+// getLocal 只从本地缓存或者 Getter 获取，用于处理其他节点的 hedged request
+func (g *Group) getLocal(key string) (ByteView, error) {
+	if v, ok := g.mainCache.get(key, g.Generation()); ok {
+		return v, nil
+	}
+	viewi, err := g.localLoader.Do(key, func() (interface{}, error) {
+		return g.getLocally(key)
+	})
+	if err != nil {
+		return ByteView{}, err
+	}
+	return viewi.(ByteView), nil
+}
+
+// fetchFromPeer 开启了 hedging 时使用 getFromPeerHedged
+func (g *Group) fetchFromPeer(peer PeerGetter, key string) (ByteView, error) {
+	if h, _ := g.hedger.Load().(*hedger); h != nil {
+		return g.getFromPeerHedged(h, peer, key)
+	}
+	return g.getFromPeer(context.Background(), peer, key, false)
+}
+
+// getFromPeer local 为 true 时要求对方不再转发给 owner
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string, local bool) (ByteView, error) {
 	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
+		Group:      g.name,
+		Key:        key,
+		Generation: g.Generation(),
+		Local:      local,
 	}
 	res := &pb.Response{}
 
-	err := peer.Get(req, res)
+	start := time.Now()
+	view, err := requestPeer(ctx, peer, req, res)
+	g.peerLatency.since(start)
+	if h, _ := g.hedger.Load().(*hedger); h != nil && err == nil {
+		h.record(time.Since(start))
+	}
+	if g.observer != nil {
+		g.observer.OnPeerFetch(g.name, key, peerName(peer), time.Since(start), err)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
-	return ByteView{b: res.Value}, nil
+	g.observeGeneration(res.GetGeneration())
+	view = view.withMeta(responseMeta(res))
+	view.stale = res.GetStale()
+	view.cas = res.GetCas()
+	return view, nil
+}
+
+// requestPeer 优先使用 StreamPeerGetter，大的缓存值直接读取到分块存储的 ByteView 中
+func requestPeer(ctx context.Context, peer PeerGetter, req *pb.Request, res *pb.Response) (ByteView, error) {
+	switch p := peer.(type) {
+	case StreamPeerGetter:
+		body, err := p.GetStream(ctx, req, res)
+		if err != nil {
+			return ByteView{}, err
+		}
+		defer body.Close()
+		return readByteView(body)
+	case ContextPeerGetter:
+		err := p.GetContext(ctx, req, res)
+		return ByteView{b: res.Value}, err
+	}
+	err := peer.Get(req, res)
+	return ByteView{b: res.Value}, err
+}
+
+// streamFromPeer 返回 owner 响应的 body，不经过 singleflight，也不会写入本地缓存
+func (g *Group) streamFromPeer(peer PeerGetter, sp StreamPeerGetter, key string) (*Stream, error) {
+	req := &pb.Request{
+		Group:      g.name,
+		Key:        key,
+		Generation: g.Generation(),
+	}
+	res := &pb.Response{}
+
+	start := time.Now()
+	body, err := sp.GetStream(context.Background(), req, res)
+	g.peerLatency.since(start)
+	if g.observer != nil {
+		g.observer.OnPeerFetch(g.name, key, peerName(peer), time.Since(start), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.observeGeneration(res.GetGeneration())
+	return &Stream{ReadCloser: body, Meta: responseMeta(res), Stale: res.GetStale()}, nil
+}
+
+// unixNano 零值的 time.Time 转换为 0
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano 0 转换为零值的 time.Time
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
 }
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHedgePercentile = 0.95
+	defaultHedgeMinDelay   = 10 * time.Millisecond
+	hedgeWindowSize        = 128 // 计算分位数使用的最近的样本数量
+	hedgeMinSamples        = 16  // 样本不足时使用 MinDelay
+)
+
+// HedgeOptions 从其他节点获取时的 hedged request 配置
+// owner 在一定时间内没有返回时，再向哈希环上的下一个节点(或者本地的 Getter)发送一次请求，
+// 使用先返回的结果，并取消另一个请求。
+type HedgeOptions struct {
+	Percentile float64       // 使用最近从其他节点获取耗时的这个分位数作为等待时间，默认 0.95
+	MinDelay   time.Duration // 等待时间的下限，样本不足时也使用它，默认 10ms
+	MaxDelay   time.Duration // 等待时间的上限，0 表示不限制
+	AllowLocal bool          // 没有其他节点可以发送时，通过 Getter 在本地加载
+}
+
+// hedger 记录最近从其他节点获取的耗时
+type hedger struct {
+	opts HedgeOptions
+
+	mtx     sync.Mutex
+	samples [hedgeWindowSize]time.Duration
+	n       int // 总共记录的样本数量
+}
+
+func newHedger(opts HedgeOptions) *hedger {
+	if opts.Percentile <= 0 || opts.Percentile > 1 {
+		opts.Percentile = defaultHedgePercentile
+	}
+	if opts.MinDelay <= 0 {
+		opts.MinDelay = defaultHedgeMinDelay
+	}
+	return &hedger{opts: opts}
+}
+
+func (h *hedger) record(d time.Duration) {
+	h.mtx.Lock()
+	h.samples[h.n%hedgeWindowSize] = d
+	h.n++
+	h.mtx.Unlock()
+}
+
+// delay 返回发送 hedged request 之前需要等待的时间
+func (h *hedger) delay() time.Duration {
+	h.mtx.Lock()
+	n := h.n
+	if n > hedgeWindowSize {
+		n = hedgeWindowSize
+	}
+	samples := make([]time.Duration, n)
+	copy(samples, h.samples[:n])
+	h.mtx.Unlock()
+
+	d := h.opts.MinDelay
+	if n >= hedgeMinSamples {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		if p := samples[int(float64(n-1)*h.opts.Percentile)]; p > d {
+			d = p
+		}
+	}
+	if h.opts.MaxDelay > 0 && d > h.opts.MaxDelay {
+		d = h.opts.MaxDelay
+	}
+	return d
+}
+
+// SetHedging 开启 hedged request，opts 为 nil 时关闭，可以在运行时修改
+func (g *Group) SetHedging(opts *HedgeOptions) {
+	var h *hedger
+	if opts != nil {
+		h = newHedger(*opts)
+	}
+	g.hedger.Store(h)
+}
+
+// getFromPeerHedged 向 owner 发送请求，超过等待时间没有返回时再向下一个节点发送一次
+func (g *Group) getFromPeerHedged(h *hedger, peer PeerGetter, key string) (ByteView, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // 取消落后的请求
+
+	type result struct {
+		value ByteView
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	go func() {
+		value, err := g.getFromPeer(ctx, peer, key, false)
+		results <- result{value, err, false}
+	}()
+
+	timer := time.NewTimer(h.delay())
+	defer timer.Stop()
+	fire := timer.C
+
+	pending, hedged := 1, false
+	var firstErr error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if r.hedge {
+					g.Stats.HedgeWins.Add(1)
+				}
+				return r.value, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			// owner 在 hedge 之前就失败了，交给 load 的 fallback 处理
+			if pending == 0 || !hedged {
+				return ByteView{}, firstErr
+			}
+		case <-fire:
+			fire = nil
+			load, ok := g.hedgeTarget(ctx, h, peer, key)
+			if !ok {
+				continue
+			}
+			hedged = true
+			pending++
+			g.Stats.HedgesFired.Add(1)
+			go func() {
+				value, err := load()
+				results <- result{value, err, true}
+			}()
+		}
+	}
+}
+
+// hedgeTarget 选择哈希环上 owner 之后的节点，没有时根据 AllowLocal 决定是否在本地加载
+func (g *Group) hedgeTarget(ctx context.Context, h *hedger, owner PeerGetter, key string) (func() (ByteView, error), bool) {
+	if picker, ok := g.peers.(ReplicaPicker); ok {
+		for _, peer := range picker.PickReplicas(key, 2) {
+			if peer != owner {
+				peer := peer
+				return func() (ByteView, error) { return g.getFromPeer(ctx, peer, key, true) }, true
+			}
+		}
+	}
+	if h.opts.AllowLocal {
+		return func() (ByteView, error) { return g.getLocally(key) }, true
+	}
+	return nil, false
+}
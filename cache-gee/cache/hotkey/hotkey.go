@@ -0,0 +1,206 @@
+// Package hotkey 使用 count-min sketch 和 top-K 堆找出访问最多的 key
+package hotkey
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+const (
+	defaultK        = 32
+	defaultWidth    = 2048
+	defaultDepth    = 4
+	defaultHalfLife = time.Minute
+)
+
+// Options Tracker 的配置
+type Options struct {
+	K        int           // 记录的热点 key 数量，默认 32
+	Width    int           // sketch 每一行的计数器数量，向上取整到 2 的幂，默认 2048
+	Depth    int           // sketch 的行数，默认 4
+	HalfLife time.Duration // 每经过 HalfLife 所有计数减半，默认 1 分钟
+}
+
+// Item 热点 key 以及衰减之后的访问次数
+type Item struct {
+	Key   string `json:"key"`
+	Count uint32 `json:"count"`
+}
+
+// entry top-K 堆中的元素
+type entry struct {
+	Item
+	index int
+}
+
+// topK 按照 Count 排序的最小堆
+type topK []*entry
+
+func (h topK) Len() int           { return len(h) }
+func (h topK) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h topK) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *topK) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *topK) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Tracker 统计 key 的访问次数，不是并发安全的
+// 计数只会高估不会低估，同一个 key 的误差随着 Width 增大而减小
+type Tracker struct {
+	k        int
+	mask     uint32
+	rows     [][]uint32
+	halfLife time.Duration
+	next     time.Time // 下一次衰减的时间
+
+	heap topK
+	keys map[string]*entry
+}
+
+// New 创建 Tracker
+func New(opts Options) *Tracker {
+	if opts.K <= 0 {
+		opts.K = defaultK
+	}
+	if opts.Width <= 0 {
+		opts.Width = defaultWidth
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = defaultDepth
+	}
+	if opts.HalfLife <= 0 {
+		opts.HalfLife = defaultHalfLife
+	}
+	width := 1
+	for width < opts.Width {
+		width <<= 1
+	}
+
+	t := &Tracker{
+		k:        opts.K,
+		mask:     uint32(width - 1),
+		rows:     make([][]uint32, opts.Depth),
+		halfLife: opts.HalfLife,
+		keys:     make(map[string]*entry, opts.K),
+	}
+	for i := range t.rows {
+		t.rows[i] = make([]uint32, width)
+	}
+	return t
+}
+
+// Add 记录 key 在 now 时被访问一次，返回 key 的估计访问次数
+func (t *Tracker) Add(key string, now time.Time) uint32 {
+	t.decay(now)
+
+	// conservative update：只增加最小的计数器，减小哈希冲突带来的高估
+	h1, h2 := hash(key)
+	est := ^uint32(0)
+	for i, row := range t.rows {
+		if c := row[(h1+uint32(i)*h2)&t.mask]; c < est {
+			est = c
+		}
+	}
+	if est < ^uint32(0) {
+		est++
+	}
+	for i, row := range t.rows {
+		if idx := (h1 + uint32(i)*h2) & t.mask; row[idx] < est {
+			row[idx] = est
+		}
+	}
+
+	if e, ok := t.keys[key]; ok {
+		e.Count = est
+		heap.Fix(&t.heap, e.index)
+	} else if len(t.heap) < t.k {
+		e := &entry{Item: Item{Key: key, Count: est}}
+		heap.Push(&t.heap, e)
+		t.keys[key] = e
+	} else if low := t.heap[0]; est > low.Count {
+		// 替换掉计数最小的 key，复用 entry 避免分配
+		delete(t.keys, low.Key)
+		low.Key, low.Count = key, est
+		heap.Fix(&t.heap, 0)
+		t.keys[key] = low
+	}
+	return est
+}
+
+// Count 返回 key 在 top-K 中的访问次数，不在 top-K 中时返回 false
+func (t *Tracker) Count(key string) (uint32, bool) {
+	e, ok := t.keys[key]
+	if !ok {
+		return 0, false
+	}
+	return e.Count, true
+}
+
+// Top 返回按照访问次数从大到小排序的热点 key
+func (t *Tracker) Top(now time.Time) []Item {
+	t.decay(now)
+	items := make([]Item, 0, len(t.heap))
+	for _, e := range t.heap {
+		if e.Count > 0 {
+			items = append(items, e.Item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+	return items
+}
+
+// decay 每经过 halfLife 所有计数减半，很久没有访问时直接清零
+func (t *Tracker) decay(now time.Time) {
+	if t.next.IsZero() {
+		t.next = now.Add(t.halfLife)
+		return
+	}
+	if now.Before(t.next) {
+		return
+	}
+	shift := uint(1 + now.Sub(t.next)/t.halfLife)
+	if shift > 32 {
+		shift = 32
+	}
+	t.next = t.next.Add(time.Duration(shift) * t.halfLife)
+	if !now.Before(t.next) {
+		t.next = now.Add(t.halfLife)
+	}
+
+	for _, row := range t.rows {
+		for i := range row {
+			row[i] = uint32(uint64(row[i]) >> shift)
+		}
+	}
+	// 所有计数同时减半，堆的顺序不变
+	for _, e := range t.heap {
+		e.Count = uint32(uint64(e.Count) >> shift)
+	}
+}
+
+// hash 64 位 FNV-1a，高低 32 位分别作为两个哈希函数组合出每一行的位置
+func hash(key string) (uint32, uint32) {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return uint32(h), uint32(h>>32) | 1
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"cache/hotkey"
+)
+
+const (
+	defaultHotThreshold = 100
+	defaultMirrorTTL    = time.Second
+)
+
+// HotKeyOptions 热点 key 统计的配置
+type HotKeyOptions struct {
+	K        int           // 记录的热点 key 数量，默认 32
+	Width    int           // count-min sketch 每一行的计数器数量，默认 2048
+	Depth    int           // count-min sketch 的行数，默认 4
+	HalfLife time.Duration // 每经过 HalfLife 访问次数减半，默认 1 分钟
+
+	// Threshold 在 top-K 中并且衰减之后的访问次数达到它的 key 被认为是热点，默认 100
+	Threshold uint32
+	// Mirror 为 true 时，从 owner 获取的热点 key 在本地保存一份，分担 owner 的压力。
+	// owner 上的 Set、Remove 不会通知其他节点，本地的副本最多在 MirrorTTL 之后过期，默认 1s
+	Mirror    bool
+	MirrorTTL time.Duration
+}
+
+// HotKey 热点 key 以及衰减之后的访问次数
+type HotKey = hotkey.Item
+
+// hotKeys 带锁的 hotkey.Tracker
+type hotKeys struct {
+	opts HotKeyOptions
+
+	mtx     sync.Mutex
+	tracker *hotkey.Tracker
+}
+
+func newHotKeys(opts HotKeyOptions) *hotKeys {
+	if opts.Threshold == 0 {
+		opts.Threshold = defaultHotThreshold
+	}
+	if opts.MirrorTTL <= 0 {
+		opts.MirrorTTL = defaultMirrorTTL
+	}
+	return &hotKeys{
+		opts: opts,
+		tracker: hotkey.New(hotkey.Options{
+			K:        opts.K,
+			Width:    opts.Width,
+			Depth:    opts.Depth,
+			HalfLife: opts.HalfLife,
+		}),
+	}
+}
+
+func (h *hotKeys) add(key string) {
+	now := time.Now()
+	h.mtx.Lock()
+	h.tracker.Add(key, now)
+	h.mtx.Unlock()
+}
+
+func (h *hotKeys) hot(key string) bool {
+	h.mtx.Lock()
+	n, ok := h.tracker.Count(key)
+	h.mtx.Unlock()
+	return ok && n >= h.opts.Threshold
+}
+
+func (h *hotKeys) top() []HotKey {
+	now := time.Now()
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.tracker.Top(now)
+}
+
+// SetHotKeys 开启热点 key 统计，opts 为 nil 时关闭，重新设置时之前的统计会被清空
+func (g *Group) SetHotKeys(opts *HotKeyOptions) {
+	var h *hotKeys
+	if opts != nil {
+		h = newHotKeys(*opts)
+	}
+	g.hotKeys.Store(h)
+}
+
+// HotKeys 返回当前节点上访问次数最多的 key，按照访问次数从大到小排序，没有开启时返回 nil
+func (g *Group) HotKeys() []HotKey {
+	if h, _ := g.hotKeys.Load().(*hotKeys); h != nil {
+		return h.top()
+	}
+	return nil
+}
+
+// IsHot key 是否是当前节点上的热点 key
+func (g *Group) IsHot(key string) bool {
+	h, _ := g.hotKeys.Load().(*hotKeys)
+	return h != nil && h.hot(key)
+}
+
+// recordGet 记录一次 Get
+func (g *Group) recordGet(key string) {
+	if h, _ := g.hotKeys.Load().(*hotKeys); h != nil {
+		h.add(key)
+	}
+}
+
+// mirror 从 owner 获取的热点 key 在本地保存一份，过期时间不超过 MirrorTTL
+func (g *Group) mirror(key string, v ByteView) {
+	h, _ := g.hotKeys.Load().(*hotKeys)
+	if h == nil || !h.opts.Mirror || v.stale || !h.hot(key) {
+		return
+	}
+	if expire := time.Now().Add(h.opts.MirrorTTL); v.e.IsZero() || v.e.After(expire) {
+		v.e = expire
+	}
+	g.Stats.HotKeyMirrors.Add(1)
+	g.populateCache(key, v)
+}
+
+// hotEntries 返回本地缓存中的热点 key，不包括 stale 的值
+func (g *Group) hotEntries() map[string]Entry {
+	h, _ := g.hotKeys.Load().(*hotKeys)
+	if h == nil {
+		return nil
+	}
+	entries := make(map[string]Entry)
+	for _, item := range h.top() {
+		if item.Count < h.opts.Threshold {
+			continue
+		}
+		v, ok := g.mainCache.get(item.Key, g.Generation())
+		if !ok || v.stale {
+			continue
+		}
+		entries[item.Key] = Entry{Value: v.ByteSlice(), Tags: g.mainCache.tagsOf(item.Key), Meta: v.Meta()}
+	}
+	return entries
+}
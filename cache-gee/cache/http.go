@@ -1,13 +1,20 @@
 package cache
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 
@@ -24,34 +31,171 @@ const (
 	defaultBasePath = "/distributed_cache/"
 	defaultReplicas = 50
 	pattern         = "/bathPath/{groupName}/{key}"
+
+	// 大于等于它的缓存值在对方允许时以流的方式返回
+	defaultStreamThreshold = 1 << 20
+	// streamContentType 以流的方式返回时响应体直接是缓存值，其他字段放在 header 中
+	streamContentType = "application/x-cache-value"
+	headerGeneration  = "X-Cache-Generation"
+	headerExpire      = "X-Cache-Expire"
+	headerVersion     = "X-Cache-Version"
+	headerType        = "X-Cache-Content-Type"
+	headerEncoding    = "X-Cache-Content-Encoding"
+	headerStale       = "X-Cache-Stale"
+	headerFlags       = "X-Cache-Flags"
+	headerCas         = "X-Cache-Cas"
+	headerRemoved     = "X-Cache-Removed" // DELETE 时 key 存在于缓存中
+
+	tagsPrefix  = "tags/"  // POST /bathPath/{groupName}/tags/{tag}
+	casPrefix   = "cas/"   // POST /bathPath/{groupName}/cas/{key} body: pb.CasRequest
+	incrPrefix  = "incr/"  // POST /bathPath/{groupName}/incr/{key} body: pb.IncrRequest
+	touchPrefix = "touch/" // POST /bathPath/{groupName}/touch/{key} body: pb.TouchRequest
+	leavePath   = "_leave" // POST /bathPath/_leave body: pb.Request，key 为下线节点的地址
+
+	// 节点之间的请求集中在少数几个地址上，http.DefaultTransport 每个 host 只保留 2 个空闲连接
+	defaultMaxIdleConnsPerHost = 32
 )
 
 type HttpPool struct {
 	// eg: http://localhost:9999/distributed_cache/
 	self        string // 记录自己的地址 ip:port or website url
 	basePath    string // 作为通信地址的开头，用于节点的访问
+	opts        HttpPoolOptions
+	client      *http.Client // httpGetter 访问其他节点时使用
+	signer      *signer      // 不为 nil 时所有节点间请求都需要签名
 	mtx         sync.Mutex
 	peers       *consistenthash.Map    // 一致性哈希算法的 Map，用来根据具体的 key 选择节点。
 	httpGetters map[string]*httpGetter // key: http://localhost:9999
+	addrs       []string               // Set 设置的节点，按照加入的顺序
+
+	drainMtx sync.RWMutex
+	draining bool           // Shutdown 之后拒绝其他节点的请求
+	active   sync.WaitGroup // 正在处理的请求
+}
+
+// HttpPoolOptions HttpPool 的可选配置
+type HttpPoolOptions struct {
+	// TLS 不为 nil 时节点之间使用 https 通信，peer 地址需要以 https:// 开头
+	TLS *TLSOptions
+
+	// Secret 不为空时节点之间的请求使用 HMAC 签名，所有节点需要使用同一个密钥
+	Secret []byte
+	// MaxClockSkew 允许的最大时钟偏差，默认 30s
+	MaxClockSkew time.Duration
+	// MaxBodyBytes 校验签名时读取的请求体的上限，超出时返回 413，默认 64MB
+	MaxBodyBytes int64
+
+	// Registry 处理其他节点的请求时查找 group 使用，默认为 DefaultRegistry
+	Registry *Registry
+
+	// BasePath 节点之间通信的路径前缀，默认为 /distributed_cache/，所有节点需要一致。
+	// 挂载到自定义的 http.ServeMux 时使用相同的 pattern，例如 mux.Handle(opts.BasePath, pool)
+	BasePath string
+	// Replicas 每个节点在哈希环上的虚拟节点数量，默认 50
+	Replicas int
+	// HashFn 哈希环使用的哈希函数，默认 crc32.ChecksumIEEE
+	HashFn consistenthash.Hash
+
+	// Transport 访问其他节点使用的 http.RoundTripper，设置后 TLS 中的客户端配置不再生效
+	Transport http.RoundTripper
+	// Timeout 访问其他节点时单个请求的超时时间，0 表示不超时。
+	// 以流的方式返回的缓存值只限制收到响应 header 之前的时间以及每次读取响应体的等待时间
+	Timeout time.Duration
+
+	// StreamThreshold 大于等于它的缓存值以 chunked 的方式直接返回，不再编码为 protobuf，
+	// 默认 1MB，小于 0 时不使用
+	StreamThreshold int
+
+	// HandOffHotKeys 为 true 时 Shutdown 把自己负责的热点 key 写入它们新的 owner，
+	// 只对开启了 SetHotKeys 的 group 生效
+	HandOffHotKeys bool
 }
 
 func NewHttpPool(self string) *HttpPool {
-	return &HttpPool{
-		self:     self,
-		basePath: defaultBasePath,
+	return NewHttpPoolOpts(self, nil)
+}
+
+// NewHttpPoolOpts 使用给定的配置创建 HttpPool，opts 为 nil 时等同于 NewHttpPool
+func NewHttpPoolOpts(self string, opts *HttpPoolOptions) *HttpPool {
+	h := &HttpPool{self: self}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	h.basePath = defaultBasePath
+	if p := h.opts.BasePath; p != "" {
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		if !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+		h.basePath = p
+	}
+	if h.opts.Replicas <= 0 {
+		h.opts.Replicas = defaultReplicas
 	}
+	if h.opts.StreamThreshold == 0 {
+		h.opts.StreamThreshold = defaultStreamThreshold
+	}
+
+	transport := h.opts.Transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+		if h.opts.TLS != nil {
+			t.TLSClientConfig = h.opts.TLS.ClientConfig()
+		}
+		transport = t
+	}
+	// 超时由 httpGetter 通过 context 控制，http.Client.Timeout 会中断读取时间较长的流
+	h.client = &http.Client{Transport: transport}
+	if len(h.opts.Secret) > 0 {
+		h.signer = newSigner(h.opts.Secret, h.opts.MaxClockSkew, h.opts.MaxBodyBytes)
+	}
+	return h
+}
+
+// ServerTLSConfig 返回监听端使用的 tls.Config，没有配置 TLS 时返回 nil
+func (h *HttpPool) ServerTLSConfig() *tls.Config {
+	if h.opts.TLS == nil {
+		return nil
+	}
+	return h.opts.TLS.ServerConfig()
 }
 
 func (h *HttpPool) Set(peers ...string) {
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
+	h.setLocked(peers)
+}
 
-	h.peers = consistenthash.New(defaultReplicas, nil)
+func (h *HttpPool) setLocked(peers []string) {
+	h.peers = consistenthash.New(h.opts.Replicas, h.opts.HashFn)
 	h.peers.Add(peers...)
 	h.httpGetters = make(map[string]*httpGetter, len(peers))
 	for _, peer := range peers {
-		h.httpGetters[peer] = &httpGetter{baseURL: peer + h.basePath}
+		h.httpGetters[peer] = &httpGetter{baseURL: peer + h.basePath, client: h.client, signer: h.signer, timeout: h.opts.Timeout}
 	}
+	h.addrs = append([]string(nil), peers...)
+}
+
+// removePeer 从哈希环中删除下线的节点，不存在或者是自己时返回 false
+func (h *HttpPool) removePeer(addr string) bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if _, ok := h.httpGetters[addr]; !ok || addr == h.self {
+		return false
+	}
+	peers := make([]string, 0, len(h.addrs))
+	for _, peer := range h.addrs {
+		if peer != addr {
+			peers = append(peers, peer)
+		}
+	}
+	h.setLocked(peers)
+	return true
 }
 
 // PickPeer picks a peer according to key
@@ -59,6 +203,9 @@ func (h *HttpPool) PickPeer(key string) (peer PeerGetter, ok bool) {
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
 
+	if h.peers == nil {
+		return nil, false
+	}
 	if peer := h.peers.Get(key); peer != "" && peer != h.self {
 		h.Log("Pick peer %s", peer)
 		return h.httpGetters[peer], true
@@ -66,50 +213,316 @@ func (h *HttpPool) PickPeer(key string) (peer PeerGetter, ok bool) {
 	return nil, false
 }
 
+// PickReplicas 按照哈希环的顺序返回 key 对应的至多 n 个节点，不包括自己
+func (h *HttpPool) PickReplicas(key string, n int) []PeerGetter {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.peers == nil {
+		return nil
+	}
+	var peers []PeerGetter
+	for _, addr := range h.peers.GetN(key, n+1) {
+		if addr != h.self && len(peers) < n {
+			peers = append(peers, h.httpGetters[addr])
+		}
+	}
+	return peers
+}
+
+// GetAll 返回除自己以外的所有节点
+func (h *HttpPool) GetAll() []PeerGetter {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	var peers []PeerGetter
+	for addr, getter := range h.httpGetters {
+		if addr != h.self {
+			peers = append(peers, getter)
+		}
+	}
+	return peers
+}
+
+// ringSize 返回真实节点以及虚拟节点的数量
+func (h *HttpPool) ringSize() (peers, vnodes int) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.peers == nil {
+		return 0, 0
+	}
+	return len(h.httpGetters), h.peers.Len()
+}
+
 // Log info with server name
 func (h *HttpPool) Log(format string, v ...interface{}) {
 	log.Printf("[Server %s] %s", h.self, fmt.Sprintf(format, v...))
 }
 
 // ServeHTTP impl http url handler
-// only handler starWith HttpPool.basePath
+// only handler starWith HttpPool.basePath, other paths get 404
 func (h *HttpPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	method := r.Method
 	if !strings.HasPrefix(path, h.basePath) {
-		panic("HttpPool serving unexpected path: " + path)
+		h.Log("unexpected path: %s", path)
+		http.NotFound(w, r)
+		return
 	}
 	h.Log("%s %s", method, path)
 
-	// GET /bathPath/{groupName}/{key}
-	if strings.HasPrefix(path, h.basePath) && method == http.MethodGet {
+	if !h.begin() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.active.Done()
+
+	if err := h.authenticate(r); err != nil {
+		h.Log("reject %s %s: %v", method, path, err)
+		if errors.Is(err, errBodyTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if method == http.MethodPost && path[len(h.basePath):] == leavePath {
+		h.handlerLeave(w, r)
+		return
+	}
+
+	switch method {
+	case http.MethodGet: // GET /bathPath/{groupName}/{key}
 		h.handlerGet(path, w, r)
+	case http.MethodPut: // PUT /bathPath/{groupName}/{key} body: pb.SetRequest
+		h.handlerSet(path, w, r)
+	case http.MethodDelete: // DELETE /bathPath/{groupName}/{key}
+		h.handlerRemove(path, w, r)
+	case http.MethodPost: // POST /bathPath/{groupName}/generation body: pb.Request
+		//                   POST /bathPath/{groupName}/tags/{tag}
+		//                   POST /bathPath/{groupName}/cas/{key} body: pb.CasRequest
+		//                   POST /bathPath/{groupName}/incr/{key} body: pb.IncrRequest
+		//                   POST /bathPath/{groupName}/touch/{key} body: pb.TouchRequest
+		//                   POST /bathPath/_leave body: pb.Request
+		h.handlerPost(path, w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-func (h *HttpPool) handlerGet(path string, w http.ResponseWriter, r *http.Request) {
+// authenticate 校验对端的客户端证书以及请求签名
+func (h *HttpPool) authenticate(r *http.Request) error {
+	if h.opts.TLS != nil && h.opts.TLS.Mutual {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return fmt.Errorf("client certificate required")
+		}
+	}
+	if h.signer != nil {
+		return h.signer.verify(r)
+	}
+	return nil
+}
+
+// parsePath 解析出 group 和 key，失败时直接写入错误响应
+func (h *HttpPool) parsePath(path string, w http.ResponseWriter) (*Group, string, bool) {
 	// see pattern
 	parts := strings.SplitN(path[len(h.basePath):], "/", 2)
 	if len(parts) != 2 {
 		http.Error(w, "bad request", http.StatusBadRequest)
-		return
+		return nil, "", false
 	}
 	groupName := parts[0]
 	key := parts[1]
 
-	group := GetGroup(groupName)
+	group := h.opts.Registry.orDefault().GetGroup(groupName)
 	if group == nil {
 		http.Error(w, "no such group: "+groupName, http.StatusNotFound)
+		return nil, "", false
+	}
+	return group, key, true
+}
+
+func (h *HttpPool) handlerGet(path string, w http.ResponseWriter, r *http.Request) {
+	group, key, ok := h.parsePath(path, w)
+	if !ok {
+		return
+	}
+	group.Stats.ServerRequests.Add(1)
+	if gen, err := strconv.ParseInt(r.URL.Query().Get("generation"), 10, 64); err == nil {
+		group.observeGeneration(gen)
+	}
+
+	var view ByteView
+	var err error
+	if r.URL.Query().Get("local") == "1" {
+		view, err = group.getLocal(key)
+	} else {
+		view, err = group.Get(key)
+	}
+	if errors.Is(err, ErrOverloaded) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 对方支持时大的缓存值直接写入响应，不需要再拷贝一份
+	if r.URL.Query().Get("stream") == "1" && h.opts.StreamThreshold > 0 && view.Len() >= h.opts.StreamThreshold {
+		w.Header().Set("Content-Type", streamContentType)
+		w.Header().Set(headerGeneration, strconv.FormatInt(group.Generation(), 10))
+		setStreamHeaders(w.Header(), view.Meta())
+		if view.Stale() {
+			w.Header().Set(headerStale, "1")
+		}
+		if view.cas != 0 {
+			w.Header().Set(headerCas, strconv.FormatUint(view.cas, 10))
+		}
+		view.WriteTo(w)
 		return
 	}
 
-	view, err := group.Get(key)
+	m := view.Meta()
+	body, err := proto.Marshal(&pb.Response{
+		Value:           view.ByteSlice(),
+		Generation:      group.Generation(),
+		Expire:          unixNano(m.Expire),
+		Version:         m.Version,
+		ContentType:     m.ContentType,
+		ContentEncoding: m.ContentEncoding,
+		Stale:           view.Stale(),
+		Cas:             view.cas,
+		Flags:           m.Flags,
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(body)
+}
+
+func (h *HttpPool) handlerSet(path string, w http.ResponseWriter, r *http.Request) {
+	group, key, ok := h.parsePath(path, w)
+	if !ok {
+		return
+	}
+	in := &pb.SetRequest{}
+	if !readProto(w, r, in) {
+		return
+	}
+	// 请求已经由 owner 接收，这里只写入本地，避免环不一致时来回转发
+	e := Entry{
+		Value: in.GetValue(),
+		Tags:  in.GetTags(),
+		Meta: Meta{
+			Expire:          fromUnixNano(in.GetExpire()),
+			Version:         in.GetVersion(),
+			ContentType:     in.GetContentType(),
+			ContentEncoding: in.GetContentEncoding(),
+			Flags:           in.GetFlags(),
+		},
+	}
+	if in.GetFill() {
+		group.fillLocally(key, e)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := group.setLocally(key, e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpPool) handlerPost(path string, w http.ResponseWriter, r *http.Request) {
+	group, op, ok := h.parsePath(path, w)
+	if !ok {
+		return
+	}
+	switch {
+	case op == "generation":
+		in := &pb.Request{}
+		if !readProto(w, r, in) {
+			return
+		}
+		group.observeGeneration(in.GetGeneration())
+	case strings.HasPrefix(op, tagsPrefix) && len(op) > len(tagsPrefix):
+		group.invalidateTagLocally(op[len(tagsPrefix):])
+	case strings.HasPrefix(op, casPrefix) && len(op) > len(casPrefix):
+		h.handlerCAS(group, op[len(casPrefix):], w, r)
+		return
+	case strings.HasPrefix(op, incrPrefix) && len(op) > len(incrPrefix):
+		h.handlerIncr(group, op[len(incrPrefix):], w, r)
+		return
+	case strings.HasPrefix(op, touchPrefix) && len(op) > len(touchPrefix):
+		in := &pb.TouchRequest{}
+		if !readProto(w, r, in) {
+			return
+		}
+		found := group.touchLocally(op[len(touchPrefix):], fromUnixNano(in.GetExpire()))
+		writeProto(w, &pb.TouchResponse{Found: found})
+		return
+	default:
+		http.Error(w, "unknown operation: "+op, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerCAS 版本不一致时仍然返回 200，由 CasResponse.Swapped 区分
+func (h *HttpPool) handlerCAS(group *Group, key string, w http.ResponseWriter, r *http.Request) {
+	in := &pb.CasRequest{}
+	if !readProto(w, r, in) {
+		return
+	}
+	e := Entry{
+		Value: in.GetValue(),
+		Tags:  in.GetTags(),
+		Meta: Meta{
+			Expire:          fromUnixNano(in.GetExpire()),
+			Version:         in.GetVersion(),
+			ContentType:     in.GetContentType(),
+			ContentEncoding: in.GetContentEncoding(),
+			Flags:           in.GetFlags(),
+		},
+	}
+	out := &pb.CasResponse{Swapped: true}
+	cas, err := group.compareAndSetLocally(key, in.GetExpected(), e)
+	var conflict *VersionConflictError
+	if errors.As(err, &conflict) {
+		out.Swapped, cas = false, conflict.Actual
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out.Cas = cas
+	writeProto(w, out)
+}
 
-	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
+// handlerIncr 值不是整数时仍然返回 200，由 IncrResponse.NotInteger 区分
+func (h *HttpPool) handlerIncr(group *Group, key string, w http.ResponseWriter, r *http.Request) {
+	in := &pb.IncrRequest{}
+	if !readProto(w, r, in) {
+		return
+	}
+	out := &pb.IncrResponse{}
+	n, err := group.incrLocally(key, in.GetDelta(), time.Duration(in.GetTtl()))
+	if errors.Is(err, ErrNotInteger) {
+		out.NotInteger = true
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out.Value = n
+	writeProto(w, out)
+}
+
+// writeProto 编码并写入响应
+func writeProto(w http.ResponseWriter, m proto.Message) {
+	body, err := proto.Marshal(m)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -118,29 +531,127 @@ func (h *HttpPool) handlerGet(path string, w http.ResponseWriter, r *http.Reques
 	w.Write(body)
 }
 
+// readProto 读取并解码请求体，失败时直接写入错误响应
+func readProto(w http.ResponseWriter, r *http.Request, m proto.Message) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err = proto.Unmarshal(body, m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *HttpPool) handlerRemove(path string, w http.ResponseWriter, r *http.Request) {
+	group, key, ok := h.parsePath(path, w)
+	if !ok {
+		return
+	}
+	existed, err := group.removeLocally(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existed {
+		w.Header().Set(headerRemoved, "1")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 type httpGetter struct {
 	baseURL string
+	client  *http.Client
+	signer  *signer
+	timeout time.Duration
+}
+
+// String 返回 peer 的地址
+func (h *httpGetter) String() string {
+	return h.baseURL
 }
 
 // Get  用于从对应 group 查找缓存值。
 func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
-	u := fmt.Sprintf(
-		"%v%v/%v",
-		h.baseURL,
-		url.QueryEscape(in.GetGroup()),
-		url.QueryEscape(in.GetKey()),
-	)
-	res, err := http.Get(u)
+	return h.GetContext(context.Background(), in, out)
+}
+
+// GetContext 与 Get 相同，ctx 被取消时请求会立即结束
+func (h *httpGetter) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	res, err := h.do(ctx, http.MethodGet, in.GetGroup(), in.GetKey(), getQuery(in), nil)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
+	return decodeResponse(res.Body, out)
+}
+
+// GetStream 与 GetContext 相同，但是允许对方以流的方式返回大的缓存值
+func (h *httpGetter) GetStream(ctx context.Context, in *pb.Request, out *pb.Response) (io.ReadCloser, error) {
+	query := getQuery(in)
+	query.Set("stream", "1")
+	res, err := h.sendStream(ctx, h.url(in.GetGroup(), in.GetKey(), query))
+	if err != nil {
+		return nil, err
+	}
+	if res.Header.Get("Content-Type") == streamContentType {
+		out.Generation, _ = strconv.ParseInt(res.Header.Get(headerGeneration), 10, 64)
+		readStreamHeaders(res.Header, out)
+		return res.Body, nil
+	}
+
+	// 缓存值较小或者对方不支持，仍然是 protobuf
+	defer res.Body.Close()
+	if err = decodeResponse(res.Body, out); err != nil {
+		return nil, err
+	}
+	value := out.Value
+	out.Value = nil
+	return ioutil.NopCloser(bytes.NewReader(value)), nil
+}
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned: %v", res.Status)
+// setStreamHeaders 以流的方式返回时元信息放在 header 中
+func setStreamHeaders(h http.Header, m Meta) {
+	if !m.Expire.IsZero() {
+		h.Set(headerExpire, strconv.FormatInt(m.Expire.UnixNano(), 10))
+	}
+	if m.Version != "" {
+		h.Set(headerVersion, m.Version)
 	}
+	if m.ContentType != "" {
+		h.Set(headerType, m.ContentType)
+	}
+	if m.ContentEncoding != "" {
+		h.Set(headerEncoding, m.ContentEncoding)
+	}
+	if m.Flags != 0 {
+		h.Set(headerFlags, strconv.FormatUint(uint64(m.Flags), 10))
+	}
+}
 
-	bytes, err := ioutil.ReadAll(res.Body)
+func readStreamHeaders(h http.Header, out *pb.Response) {
+	out.Expire, _ = strconv.ParseInt(h.Get(headerExpire), 10, 64)
+	out.Version = h.Get(headerVersion)
+	out.ContentType = h.Get(headerType)
+	out.ContentEncoding = h.Get(headerEncoding)
+	out.Stale = h.Get(headerStale) == "1"
+	flags, _ := strconv.ParseUint(h.Get(headerFlags), 10, 32)
+	out.Flags = uint32(flags)
+	out.Cas, _ = strconv.ParseUint(h.Get(headerCas), 10, 64)
+}
+
+func getQuery(in *pb.Request) url.Values {
+	query := url.Values{"generation": {strconv.FormatInt(in.GetGeneration(), 10)}}
+	if in.GetLocal() {
+		query.Set("local", "1")
+	}
+	return query
+}
+
+func decodeResponse(r io.Reader, out proto.Message) error {
+	bytes, err := ioutil.ReadAll(r)
 	if err != nil {
 		return fmt.Errorf("reading response body: %v", err)
 	}
@@ -152,5 +663,234 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 	return nil
 }
 
+// Set 写入对应节点的缓存
+func (h *httpGetter) Set(in *pb.SetRequest) error {
+	return h.SetContext(context.Background(), in)
+}
+
+// SetContext 与 Set 相同，ctx 结束时取消请求
+func (h *httpGetter) SetContext(ctx context.Context, in *pb.SetRequest) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(ctx, http.MethodPut, in.GetGroup(), in.GetKey(), nil, body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Remove 删除对应节点的缓存
+func (h *httpGetter) Remove(in *pb.Request) error {
+	_, err := h.RemoveExisted(in)
+	return err
+}
+
+// RemoveExisted 删除对应节点的缓存，同时返回 key 是否存在
+func (h *httpGetter) RemoveExisted(in *pb.Request) (bool, error) {
+	res, err := h.do(context.Background(), http.MethodDelete, in.GetGroup(), in.GetKey(), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	return res.Header.Get(headerRemoved) == "1", res.Body.Close()
+}
+
+// SetGeneration 通知对应节点 group 的 generation
+func (h *httpGetter) SetGeneration(in *pb.Request) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(context.Background(), http.MethodPost, in.GetGroup(), "generation", nil, body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// InvalidateTag 删除对应节点上带有 tag 的缓存
+func (h *httpGetter) InvalidateTag(in *pb.Request) error {
+	res, err := h.do(context.Background(), http.MethodPost, in.GetGroup(), tagsPrefix+in.GetKey(), nil, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// CompareAndSet 在对应节点上执行 compare-and-set
+func (h *httpGetter) CompareAndSet(in *pb.CasRequest, out *pb.CasResponse) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(context.Background(), http.MethodPost, in.GetGroup(), casPrefix+in.GetKey(), nil, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res.Body, out)
+}
+
+// Incr 在对应节点上修改计数器
+func (h *httpGetter) Incr(in *pb.IncrRequest, out *pb.IncrResponse) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(context.Background(), http.MethodPost, in.GetGroup(), incrPrefix+in.GetKey(), nil, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res.Body, out)
+}
+
+// Touch 修改对应节点上缓存值的过期时间
+func (h *httpGetter) Touch(in *pb.TouchRequest, out *pb.TouchResponse) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(context.Background(), http.MethodPost, in.GetGroup(), touchPrefix+in.GetKey(), nil, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res.Body, out)
+}
+
+// leave 通知对应节点 self 已经下线
+func (h *httpGetter) leave(ctx context.Context, self string) error {
+	body, err := proto.Marshal(&pb.Request{Key: self})
+	if err != nil {
+		return err
+	}
+	res, err := h.send(ctx, http.MethodPost, h.baseURL+leavePath, body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// do 发送请求，返回状态码为 2xx 的响应
+func (h *httpGetter) do(ctx context.Context, method, group, key string, query url.Values, body []byte) (*http.Response, error) {
+	return h.send(ctx, method, h.url(group, key, query), body)
+}
+
+func (h *httpGetter) url(group, key string, query url.Values) string {
+	u := fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		url.PathEscape(group),
+		url.PathEscape(key),
+	)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// send timeout 覆盖从发送请求到读取完响应体的整个过程，关闭响应体之后结束
+func (h *httpGetter) send(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	if h.timeout <= 0 {
+		return h.roundTrip(ctx, method, u, body)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	res, err := h.roundTrip(ctx, method, u, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// sendStream 以流的方式读取响应体时使用，timeout 只限制收到响应 header 之前的时间，
+// 之后每次读取等待超过 timeout 时中断，大的缓存值不会因为读取的总时间而失败
+func (h *httpGetter) sendStream(ctx context.Context, u string) (*http.Response, error) {
+	if h.timeout <= 0 {
+		return h.roundTrip(ctx, http.MethodGet, u, nil)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(h.timeout, cancel)
+	res, err := h.roundTrip(ctx, http.MethodGet, u, nil)
+	timer.Stop()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &idleBody{ReadCloser: res.Body, timer: timer, timeout: h.timeout, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody 关闭响应体时释放 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// idleBody 每次 Read 等待超过 timeout 时取消请求
+type idleBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// roundTrip 签名并发送请求，状态码不是 2xx 时返回错误
+func (h *httpGetter) roundTrip(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if h.signer != nil {
+		if err = h.signer.sign(req, body); err != nil {
+			return nil, err
+		}
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, fmt.Errorf("server returned: %v", res.Status)
+	}
+	return res, nil
+}
+
 var _ PeerPicker = (*HttpPool)(nil)
 var _ PeerGetter = (*httpGetter)(nil)
+var _ ContextPeerGetter = (*httpGetter)(nil)
+var _ StreamPeerGetter = (*httpGetter)(nil)
+var _ CASPeerGetter = (*httpGetter)(nil)
+var _ WritePeerGetter = (*httpGetter)(nil)
+var _ RemovePeerGetter = (*httpGetter)(nil)
+var _ GenerationPeerGetter = (*httpGetter)(nil)
+var _ TagPeerGetter = (*httpGetter)(nil)
+var _ CounterPeerGetter = (*httpGetter)(nil)
+var _ TouchPeerGetter = (*httpGetter)(nil)
+var _ ReplicaPicker = (*HttpPool)(nil)
+var _ PeerLister = (*HttpPool)(nil)
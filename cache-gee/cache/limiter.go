@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded Getter 的调用超出了 LoadLimits，可以使用 errors.Is 判断
+var ErrOverloaded = errors.New("overloaded")
+
+// OverloadedError 包含了被拒绝的原因
+type OverloadedError struct {
+	Group  string
+	Reason string
+}
+
+func (e *OverloadedError) Error() string {
+	return "group " + e.Group + " overloaded: " + e.Reason
+}
+
+// Is 使 errors.Is(err, ErrOverloaded) 成立
+func (e *OverloadedError) Is(target error) bool {
+	return target == ErrOverloaded
+}
+
+// LoadLimits 限制 Getter 的调用，防止冷启动时大量的 miss 压垮数据源，零值表示不限制
+type LoadLimits struct {
+	MaxConcurrent int           // 同时进行的 Getter 调用数量
+	MaxQueue      int           // 超出 MaxConcurrent 时最多排队等待的数量，0 表示不排队直接拒绝
+	MaxWait       time.Duration // 排队的最长时间，0 表示一直等待
+	Rate          float64       // 每秒允许的 Getter 调用数量(令牌桶)
+	Burst         int           // 令牌桶的容量，默认为 1
+}
+
+// loadLimiter 按照 LoadLimits 控制 Getter 的调用
+type loadLimiter struct {
+	group   string
+	limits  LoadLimits
+	stats   *Stats
+	sem     chan struct{} // MaxConcurrent 为 0 时为 nil
+	waiting int64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newLoadLimiter(group string, limits LoadLimits, stats *Stats) *loadLimiter {
+	l := &loadLimiter{group: group, limits: limits, stats: stats, last: time.Now()}
+	if limits.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, limits.MaxConcurrent)
+	}
+	if l.limits.Burst <= 0 {
+		l.limits.Burst = 1
+	}
+	l.tokens = float64(l.limits.Burst)
+	return l
+}
+
+// acquire 获取一次调用 Getter 的许可，成功时需要调用返回的 release
+// 令牌在开始时取出，因为队列已满或者排队超时被拒绝时归还
+func (l *loadLimiter) acquire() (release func(), err error) {
+	if l.limits.Rate > 0 && !l.takeToken() {
+		return nil, l.reject("rate limit exceeded")
+	}
+	if l.sem == nil {
+		return func() {}, nil
+	}
+	refuse := func(reason string) error {
+		if l.limits.Rate > 0 {
+			l.putToken()
+		}
+		return l.reject(reason)
+	}
+	release = func() { <-l.sem }
+
+	select {
+	case l.sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	// 排队等待
+	if waiting := atomic.AddInt64(&l.waiting, 1); int(waiting) > l.limits.MaxQueue {
+		atomic.AddInt64(&l.waiting, -1)
+		return nil, refuse("load queue full")
+	}
+	defer atomic.AddInt64(&l.waiting, -1)
+	l.stats.LoadsQueued.Add(1)
+
+	var timeout <-chan time.Time
+	if l.limits.MaxWait > 0 {
+		timer := time.NewTimer(l.limits.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, refuse("timed out waiting in load queue")
+	}
+}
+
+func (l *loadLimiter) takeToken() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.limits.Rate
+	if max := float64(l.limits.Burst); l.tokens > max {
+		l.tokens = max
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// putToken 归还 takeToken 取出的令牌，不超过 Burst
+func (l *loadLimiter) putToken() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.tokens++
+	if max := float64(l.limits.Burst); l.tokens > max {
+		l.tokens = max
+	}
+}
+
+func (l *loadLimiter) reject(reason string) error {
+	l.stats.LoadsRejected.Add(1)
+	return &OverloadedError{Group: l.group, Reason: reason}
+}
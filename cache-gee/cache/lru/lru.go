@@ -1,14 +1,19 @@
 package lru
 
 import (
-	"container/list"
+	"time"
 )
 
+// Cache 缓存项保存在一个 slice 中，通过下标组成双向链表，
+// 大量缓存项时堆上只有一个大的 slice，而不是每一项都有链表节点和 entry 两个对象
 type Cache struct {
-	maxBytes int64                    // 当前允许的最大内存
-	nBytes   int64                    // 已经使用的内存
-	ll       *list.List               // 双向链表
-	cache    map[string]*list.Element // 字典 key:字符串，value:对应链表中的节点
+	maxBytes int64            // 当前允许的最大内存
+	nBytes   int64            // 已经使用的内存
+	entries  []entry          // entries[0] 不使用，下标 0 表示空
+	free     []int32          // entries 中被删除、可以复用的下标
+	head     int32            // 最近使用的，链表头部
+	tail     int32            // 最久未使用的，链表尾部
+	cache    map[string]int32 // 字典 key:字符串，value:entries 中的下标
 
 	OnEvicted func(key string, value Value)
 }
@@ -16,16 +21,27 @@ type Cache struct {
 func New(maxBytes int64, onEvicted func(key string, value Value)) *Cache {
 	return &Cache{
 		maxBytes:  maxBytes,
-		ll:        list.New(),
-		cache:     make(map[string]*list.Element),
+		entries:   make([]entry, 1),
+		cache:     make(map[string]int32),
 		OnEvicted: onEvicted,
 	}
 }
 
-// entry list 存储的数据类型
+// entry 存储的数据类型
 type entry struct {
-	key   string
-	value Value
+	key        string
+	value      Value
+	added      int64 // 写入时间，unix nano
+	accessed   int64 // 最近一次访问时间，unix nano
+	prev, next int32
+}
+
+// Info 一个缓存项的元信息
+type Info struct {
+	Key      string
+	Size     int // value 的长度
+	Added    time.Time
+	Accessed time.Time
 }
 
 type Value interface {
@@ -35,47 +51,170 @@ type Value interface {
 // Get 根据key返回缓存内容，并移动到头部
 // 规定头部是使用的较多的
 // 尾部是最近使用较少的
-func (c Cache) Get(key string) (value Value, ok bool) {
-	if ele, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ele)
-		kv := ele.Value.(*entry)
-		return kv.value, true
+func (c *Cache) Get(key string) (value Value, ok bool) {
+	if i, ok := c.cache[key]; ok {
+		c.moveToFront(i)
+		e := &c.entries[i]
+		e.accessed = time.Now().UnixNano()
+		return e.value, true
+	}
+	return
+}
+
+// Peek 返回缓存内容，不会影响淘汰顺序
+func (c *Cache) Peek(key string) (value Value, ok bool) {
+	if i, ok := c.cache[key]; ok {
+		return c.entries[i].value, true
 	}
 	return
 }
 
 // RemoveOldest 移除最近使用最少的，即移除队尾的
 func (c *Cache) RemoveOldest() {
-	ele := c.ll.Back()
-	if ele != nil {
-		c.ll.Remove(ele)
-		kv := ele.Value.(*entry)
-		delete(c.cache, kv.key)
-		c.nBytes -= int64(len(kv.key)) + int64(kv.value.Len())
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value)
-		}
+	if c.tail != 0 {
+		c.removeEntry(c.tail)
+	}
+}
+
+// Remove 移除指定的key
+func (c *Cache) Remove(key string) bool {
+	if i, ok := c.cache[key]; ok {
+		c.removeEntry(i)
+		return true
+	}
+	return false
+}
+
+// Clear 移除所有缓存项，每一项都会触发 OnEvicted
+func (c *Cache) Clear() {
+	for c.tail != 0 {
+		c.RemoveOldest()
+	}
+	c.entries = c.entries[:1]
+	c.free = nil
+}
+
+func (c *Cache) removeEntry(i int32) {
+	c.unlink(i)
+	e := c.entries[i]
+	delete(c.cache, e.key)
+	c.nBytes -= int64(len(e.key)) + int64(e.value.Len())
+	// 清空引用，让 key 和 value 可以被回收
+	c.entries[i] = entry{}
+	c.free = append(c.free, i)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
 	}
 }
 
 // Add adds a value to the cache.
 func (c *Cache) Add(key string, value Value) {
-	if ele, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ele)
-		kv := ele.Value.(*entry)
-		c.nBytes += int64(value.Len()) - int64(kv.value.Len())
-		kv.value = value
+	now := time.Now().UnixNano()
+	if i, ok := c.cache[key]; ok {
+		c.moveToFront(i)
+		e := &c.entries[i]
+		c.nBytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+		e.added = now
+		e.accessed = now
 	} else {
-		ele := c.ll.PushFront(&entry{key, value})
-		c.cache[key] = ele
+		var i int32
+		if n := len(c.free); n > 0 {
+			i = c.free[n-1]
+			c.free = c.free[:n-1]
+		} else {
+			c.entries = append(c.entries, entry{})
+			i = int32(len(c.entries) - 1)
+		}
+		c.entries[i] = entry{key: key, value: value, added: now, accessed: now}
+		c.pushFront(i)
+		c.cache[key] = i
 		c.nBytes += int64(len(key)) + int64(value.Len())
 	}
+	c.evict()
+}
+
+func (c *Cache) pushFront(i int32) {
+	e := &c.entries[i]
+	e.prev, e.next = 0, c.head
+	if c.head != 0 {
+		c.entries[c.head].prev = i
+	} else {
+		c.tail = i
+	}
+	c.head = i
+}
+
+func (c *Cache) unlink(i int32) {
+	e := &c.entries[i]
+	if e.prev != 0 {
+		c.entries[e.prev].next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next != 0 {
+		c.entries[e.next].prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+	e.prev, e.next = 0, 0
+}
+
+func (c *Cache) moveToFront(i int32) {
+	if c.head != i {
+		c.unlink(i)
+		c.pushFront(i)
+	}
+}
+
+// SetMaxBytes 修改允许的最大内存，超出的部分立即淘汰，0 表示不限制
+func (c *Cache) SetMaxBytes(maxBytes int64) {
+	c.maxBytes = maxBytes
+	c.evict()
+}
+
+func (c *Cache) evict() {
 	for c.maxBytes != 0 && c.maxBytes < c.nBytes {
 		c.RemoveOldest()
 	}
 }
 
+// MaxBytes 允许的最大内存
+func (c *Cache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// Bytes 已经使用的内存
+func (c *Cache) Bytes() int64 {
+	return c.nBytes
+}
+
+// Keys 按照最近使用的顺序返回从 offset 开始的至多 limit 个key，limit <= 0 表示不限制
+func (c *Cache) Keys(offset, limit int) []string {
+	var keys []string
+	i := 0
+	for idx := c.head; idx != 0; idx = c.entries[idx].next {
+		if i++; i <= offset {
+			continue
+		}
+		if limit > 0 && len(keys) >= limit {
+			break
+		}
+		keys = append(keys, c.entries[idx].key)
+	}
+	return keys
+}
+
+// Stat 返回key的元信息，不会影响淘汰顺序
+func (c *Cache) Stat(key string) (info Info, ok bool) {
+	if i, ok := c.cache[key]; ok {
+		e := &c.entries[i]
+		return Info{Key: e.key, Size: e.value.Len(), Added: time.Unix(0, e.added), Accessed: time.Unix(0, e.accessed)}, true
+	}
+	return
+}
+
 // Len the number of cache entries
 func (c *Cache) Len() int {
-	return c.ll.Len()
+	return len(c.cache)
 }
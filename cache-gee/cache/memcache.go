@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+  MemcacheServer 使用 memcached 文本协议对外提供服务，key 的格式为 {groupName}:{key}
+  支持的命令: get gets set cas delete touch stats version quit
+  flags 与缓存值一起保存，读取时原样返回
+*/
+
+const (
+	memcacheVersion   = "1.6.0-cache"
+	maxMemcacheKeyLen = 250
+	maxMemcacheValue  = 1 << 20
+	memcacheRelExpire = 60 * 60 * 24 * 30 // 超过 30 天的 exptime 被当作 unix 时间戳
+)
+
+var errMemcacheBadFormat = errors.New("CLIENT_ERROR bad command line format")
+
+// MemcacheServer 兼容 memcached 文本协议的前端
+type MemcacheServer struct {
+	*tcpServer
+
+	// DefaultGroup 不为空时，不包含 ':' 的 key 使用这个 group
+	DefaultGroup string
+	// Registry 为 nil 时使用 DefaultRegistry
+	Registry *Registry
+}
+
+func NewMemcacheServer() *MemcacheServer {
+	return &MemcacheServer{tcpServer: newTCPServer()}
+}
+
+// ListenAndServe 监听 addr 并处理连接
+func (s *MemcacheServer) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理 l 上的连接，直到 Close 被调用
+func (s *MemcacheServer) Serve(l net.Listener) error {
+	return s.serve(l, s.serveConn)
+}
+
+func (s *MemcacheServer) serveConn(conn net.Conn) {
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	for {
+		// 命令行的长度不能超过缓冲区的大小，避免客户端一直不发送换行符占用内存
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			w.WriteString("CLIENT_ERROR line is too long\r\n")
+			w.Flush()
+			return
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Println("[MemcacheServer] read command:", err)
+			}
+			return
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 {
+			continue
+		}
+		quit, err := s.exec(r, w, fields)
+		if err != nil {
+			// 无法继续解析后续的命令
+			w.WriteString(err.Error() + "\r\n")
+			w.Flush()
+			return
+		}
+		// pipeline 中的命令全部处理完之后再一起发送
+		if r.Buffered() == 0 || quit {
+			if err = w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// exec 执行一条命令，返回 true 表示需要关闭连接，返回 error 表示连接已经无法继续使用
+func (s *MemcacheServer) exec(r *bufio.Reader, w *bufio.Writer, fields []string) (bool, error) {
+	cmd, args := fields[0], fields[1:]
+	switch cmd {
+	case "get", "gets":
+		if len(args) == 0 {
+			w.WriteString("ERROR\r\n")
+			break
+		}
+		for _, name := range args {
+			s.get(w, name, cmd == "gets")
+		}
+		w.WriteString("END\r\n")
+	case "set", "cas":
+		return false, s.set(r, w, args, cmd == "cas")
+	case "delete":
+		noreply := trimNoreply(&args)
+		if len(args) != 1 {
+			w.WriteString("ERROR\r\n")
+			break
+		}
+		reply := "DELETED"
+		if group, key, err := s.lookup(args[0]); err != nil {
+			reply = err.Error()
+		} else if existed, err := group.Remove(key); err != nil {
+			reply = "SERVER_ERROR " + err.Error()
+		} else if !existed {
+			reply = "NOT_FOUND"
+		}
+		writeReply(w, reply, noreply)
+	case "touch":
+		noreply := trimNoreply(&args)
+		if len(args) != 2 {
+			w.WriteString("ERROR\r\n")
+			break
+		}
+		exptime, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			writeReply(w, errMemcacheBadFormat.Error(), noreply)
+			break
+		}
+		writeReply(w, s.touch(args[0], memcacheExpire(exptime, time.Now())), noreply)
+	case "stats":
+		s.stats(w)
+	case "version":
+		w.WriteString("VERSION " + memcacheVersion + "\r\n")
+	case "quit":
+		return true, nil
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+	return false, nil
+}
+
+// lookup 将 {groupName}:{key} 解析为 group 和 key
+func (s *MemcacheServer) lookup(name string) (*Group, string, error) {
+	if len(name) > maxMemcacheKeyLen {
+		return nil, "", errors.New("CLIENT_ERROR key too long")
+	}
+	group, key, err := lookupGroup(s.Registry, s.DefaultGroup, name)
+	if err != nil {
+		return nil, "", errors.New("CLIENT_ERROR " + err.Error())
+	}
+	return group, key, nil
+}
+
+// get 写入 VALUE <key> <flags> <bytes> [<cas unique>]，key 不存在时什么都不写
+func (s *MemcacheServer) get(w *bufio.Writer, name string, cas bool) {
+	group, key, err := s.lookup(name)
+	if err != nil {
+		return
+	}
+	view, version, err := group.GetVersioned(key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("[MemcacheServer] get %s: %v", name, err)
+		}
+		return
+	}
+	flags := view.Meta().Flags
+	if cas {
+		fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", name, flags, view.Len(), version)
+	} else {
+		fmt.Fprintf(w, "VALUE %s %d %d\r\n", name, flags, view.Len())
+	}
+	view.WriteTo(w)
+	w.WriteString("\r\n")
+}
+
+// set <key> <flags> <exptime> <bytes> [noreply]\r\n<data>\r\n
+// cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]\r\n<data>\r\n
+func (s *MemcacheServer) set(r *bufio.Reader, w *bufio.Writer, args []string, cas bool) error {
+	noreply := trimNoreply(&args)
+	var unique uint64
+	if cas {
+		if len(args) != 5 {
+			return errMemcacheBadFormat
+		}
+		var err error
+		if unique, err = strconv.ParseUint(args[4], 10, 64); err != nil {
+			return errMemcacheBadFormat
+		}
+		args = args[:4]
+	}
+	if len(args) != 4 {
+		return errMemcacheBadFormat
+	}
+	flags, flagsErr := strconv.ParseUint(args[1], 10, 32)
+	exptime, expErr := strconv.ParseInt(args[2], 10, 64)
+	n, lenErr := strconv.Atoi(args[3])
+	if flagsErr != nil || expErr != nil || lenErr != nil || n < 0 {
+		return errMemcacheBadFormat
+	}
+	if n > maxMemcacheValue {
+		// 与 memcached 一样丢弃数据块，连接可以继续使用
+		if _, err := io.CopyN(ioutil.Discard, r, int64(n)+2); err != nil {
+			return err
+		}
+		writeReply(w, "SERVER_ERROR object too large for cache", noreply)
+		return nil
+	}
+
+	data := make([]byte, n+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		return errors.New("CLIENT_ERROR bad data chunk")
+	}
+
+	group, key, err := s.lookup(args[0])
+	if err != nil {
+		writeReply(w, err.Error(), noreply)
+		return nil
+	}
+	e := Entry{Value: data[:n], Meta: Meta{Expire: memcacheExpire(exptime, time.Now()), Flags: uint32(flags)}}
+	if cas {
+		_, err = group.CompareAndSetEntry(key, unique, e)
+	} else {
+		err = group.SetEntry(key, e)
+	}
+	writeReply(w, storeReply(err), noreply)
+	return nil
+}
+
+// storeReply set 以及 cas 的返回值
+func storeReply(err error) string {
+	var conflict *VersionConflictError
+	switch {
+	case err == nil:
+		return "STORED"
+	case errors.As(err, &conflict) && conflict.Actual == 0:
+		return "NOT_FOUND"
+	case errors.As(err, &conflict):
+		return "EXISTS"
+	}
+	return "SERVER_ERROR " + err.Error()
+}
+
+// touch 只修改 owner 上缓存值的过期时间，没有缓存的 key 返回 NOT_FOUND
+func (s *MemcacheServer) touch(name string, expire time.Time) string {
+	group, key, err := s.lookup(name)
+	if err != nil {
+		return err.Error()
+	}
+	found, err := group.Touch(key, expire)
+	if err != nil {
+		return "SERVER_ERROR " + err.Error()
+	}
+	if !found {
+		return "NOT_FOUND"
+	}
+	return "TOUCHED"
+}
+
+// stats 汇总所有 group 的统计信息，并以 <group>:<name> 的形式输出每个 group 的统计信息
+func (s *MemcacheServer) stats(w *bufio.Writer) {
+	conns, uptime := s.status()
+	now := time.Now()
+	stat := func(name string, v interface{}) {
+		fmt.Fprintf(w, "STAT %s %v\r\n", name, v)
+	}
+	stat("pid", os.Getpid())
+	stat("uptime", int64(uptime/time.Second))
+	stat("time", now.Unix())
+	stat("version", memcacheVersion)
+	stat("curr_connections", conns)
+
+	var gets, hits, misses, sets, removes, items, bytes, limit int64
+	list := s.Registry.orDefault().Groups()
+	for _, g := range list {
+		b, cacheBytes, n := g.mainCache.usage()
+		gets += g.Stats.Gets.Get()
+		hits += g.Stats.CacheHits.Get()
+		misses += g.Stats.Loads.Get()
+		sets += g.Stats.Sets.Get()
+		removes += g.Stats.Removes.Get()
+		items += int64(n)
+		bytes += b
+		limit += cacheBytes
+	}
+	stat("cmd_get", gets)
+	stat("cmd_set", sets)
+	stat("get_hits", hits)
+	stat("get_misses", misses)
+	stat("delete_hits", removes)
+	stat("curr_items", items)
+	stat("bytes", bytes)
+	stat("limit_maxbytes", limit)
+
+	for _, g := range list {
+		b, cacheBytes, n := g.mainCache.usage()
+		stat(g.name+":cmd_get", g.Stats.Gets.Get())
+		stat(g.name+":get_hits", g.Stats.CacheHits.Get())
+		stat(g.name+":get_misses", g.Stats.Loads.Get())
+		stat(g.name+":cmd_set", g.Stats.Sets.Get())
+		stat(g.name+":delete_hits", g.Stats.Removes.Get())
+		stat(g.name+":peer_loads", g.Stats.PeerLoads.Get())
+		stat(g.name+":peer_errors", g.Stats.PeerErrors.Get())
+		stat(g.name+":local_loads", g.Stats.LocalLoads.Get())
+		stat(g.name+":local_load_errors", g.Stats.LocalLoadErrs.Get())
+		stat(g.name+":curr_items", n)
+		stat(g.name+":bytes", b)
+		stat(g.name+":limit_maxbytes", cacheBytes)
+	}
+	w.WriteString("END\r\n")
+}
+
+// memcacheExpire 将 exptime 转换为过期时间
+// 0 表示永不过期，负数表示立即过期，超过 30 天的值被当作 unix 时间戳
+func memcacheExpire(exptime int64, now time.Time) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return now
+	case exptime > memcacheRelExpire:
+		return time.Unix(exptime, 0)
+	default:
+		return now.Add(time.Duration(exptime) * time.Second)
+	}
+}
+
+// trimNoreply 去掉结尾的 noreply 参数
+func trimNoreply(args *[]string) bool {
+	if n := len(*args); n > 0 && (*args)[n-1] == "noreply" {
+		*args = (*args)[:n-1]
+		return true
+	}
+	return false
+}
+
+func writeReply(w *bufio.Writer, reply string, noreply bool) {
+	if !noreply {
+		w.WriteString(reply + "\r\n")
+	}
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "cache/cachepb"
+)
+
+// Meta 缓存值的元信息，会随着缓存值在节点之间传递
+type Meta struct {
+	Expire          time.Time // 过期时间，零值表示永不过期
+	Version         string    // 版本或者 ETag
+	ContentType     string
+	ContentEncoding string
+	Flags           uint32 // memcached 协议中客户端设置的 flags，通常标记了序列化以及压缩的方式
+}
+
+// Entry 带有元信息的缓存值，用于 EntryGetter 和 Group.SetEntry
+type Entry struct {
+	Value []byte
+	Tags  []string // 用于 Group.InvalidateTag
+	Meta
+}
+
+// EntryGetter 可选接口，Getter 实现它时加载的值会带上过期时间、版本等元信息，优先于 TagGetter
+type EntryGetter interface {
+	GetEntry(key string) (Entry, error)
+}
+
+// Stream GetStream 返回的缓存值，调用方读取完毕之后需要 Close
+type Stream struct {
+	io.ReadCloser
+	Meta
+	Stale bool // 见 ByteView.Stale
+}
+
+// WriteHeaders 将元信息写入 http 响应的 header，用于对外提供缓存值的 http 服务
+func (m Meta) WriteHeaders(h http.Header) {
+	if m.ContentType != "" {
+		h.Set("Content-Type", m.ContentType)
+	}
+	if m.ContentEncoding != "" {
+		h.Set("Content-Encoding", m.ContentEncoding)
+	}
+	if m.Version != "" {
+		h.Set("ETag", strconv.Quote(m.Version))
+	}
+	if !m.Expire.IsZero() {
+		h.Set("Expires", m.Expire.UTC().Format(http.TimeFormat))
+		maxAge := int64(time.Until(m.Expire) / time.Second)
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		h.Set("Cache-Control", "max-age="+strconv.FormatInt(maxAge, 10))
+	}
+}
+
+// extra ByteView 中除过期时间以外的元信息，大部分缓存值没有，使用指针节省空间
+type extra struct {
+	version         string
+	contentType     string
+	contentEncoding string
+	flags           uint32
+}
+
+func newExtra(m Meta) *extra {
+	if m.Version == "" && m.ContentType == "" && m.ContentEncoding == "" && m.Flags == 0 {
+		return nil
+	}
+	return &extra{version: m.Version, contentType: m.ContentType, contentEncoding: m.ContentEncoding, flags: m.Flags}
+}
+
+// responseMeta 从其他节点的响应中读取元信息，旧版本的节点不会设置这些字段
+func responseMeta(res *pb.Response) Meta {
+	return Meta{
+		Expire:          fromUnixNano(res.GetExpire()),
+		Version:         res.GetVersion(),
+		ContentType:     res.GetContentType(),
+		ContentEncoding: res.GetContentEncoding(),
+		Flags:           res.GetFlags(),
+	}
+}
+
+// appendExtra 编码 extra，保存在 slab 中缓存值的后面：三个长度加内容的字段 | flags
+func appendExtra(b []byte, x *extra) []byte {
+	var n [binary.MaxVarintLen64]byte
+	for _, s := range []string{x.version, x.contentType, x.contentEncoding} {
+		b = append(b, n[:binary.PutUvarint(n[:], uint64(len(s)))]...)
+		b = append(b, s...)
+	}
+	return append(b, n[:binary.PutUvarint(n[:], uint64(x.flags))]...)
+}
+
+func decodeExtra(b []byte) *extra {
+	x, _, _ := readExtra(b)
+	return x
+}
+
+// readExtra 解码 b 开头的 extra，同时返回剩余的部分
+func readExtra(b []byte) (*extra, []byte, bool) {
+	var fields [3]string
+	for i := range fields {
+		n, m := binary.Uvarint(b)
+		if m <= 0 || uint64(len(b)-m) < n {
+			return nil, nil, false
+		}
+		fields[i] = string(b[m : m+int(n)])
+		b = b[m+int(n):]
+	}
+	flags, m := binary.Uvarint(b)
+	if m <= 0 || flags > 1<<32-1 {
+		return nil, nil, false
+	}
+	x := &extra{version: fields[0], contentType: fields[1], contentEncoding: fields[2], flags: uint32(flags)}
+	return x, b[m:], true
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+  Metrics 以 Prometheus text exposition format 输出所有 group 的统计信息
+  GET /metrics
+*/
+
+// latencyBuckets 与 Prometheus client 的默认 bucket 一致，单位为秒
+var latencyBuckets = [...]float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram 并发安全的耗时直方图，零值可以直接使用
+type histogram struct {
+	counts [len(latencyBuckets) + 1]int64 // 最后一个是 +Inf
+	sum    int64                          // 纳秒
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && d.Seconds() > latencyBuckets[i] {
+		i++
+	}
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// since 记录从 start 开始到现在的耗时
+func (h *histogram) since(start time.Time) {
+	h.observe(time.Since(start))
+}
+
+// Metrics Prometheus 的 /metrics 接口
+type Metrics struct {
+	pool *HttpPool
+
+	// Registry 为 nil 时使用 DefaultRegistry
+	Registry *Registry
+}
+
+// NewMetrics pool 不为 nil 时会额外输出哈希环的大小
+func NewMetrics(pool *HttpPool) *Metrics {
+	return &Metrics{pool: pool}
+}
+
+// ServeHTTP impl http url handler
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	bw.Flush()
+}
+
+func (m *Metrics) write(w *bufio.Writer) {
+	list := m.Registry.orDefault().Groups()
+
+	counters := []struct {
+		name, help string
+		value      func(g *Group) int64
+	}{
+		{"cache_gets_total", "Get requests, including requests from peers.", func(g *Group) int64 { return g.Stats.Gets.Get() }},
+		{"cache_hits_total", "Get requests served from the local cache.", func(g *Group) int64 { return g.Stats.CacheHits.Get() }},
+		{"cache_misses_total", "Get requests that missed the local cache.", func(g *Group) int64 { return g.Stats.Loads.Get() }},
+		{"cache_loads_total", "Loads after singleflight deduplication.", func(g *Group) int64 { return g.Stats.LoadsDeduped.Get() }},
+		{"cache_local_loads_total", "Successful loads from the Getter.", func(g *Group) int64 { return g.Stats.LocalLoads.Get() }},
+		{"cache_local_load_errors_total", "Failed loads from the Getter.", func(g *Group) int64 { return g.Stats.LocalLoadErrs.Get() }},
+		{"cache_peer_loads_total", "Successful fetches from peers.", func(g *Group) int64 { return g.Stats.PeerLoads.Get() }},
+		{"cache_peer_errors_total", "Failed fetches from peers.", func(g *Group) int64 { return g.Stats.PeerErrors.Get() }},
+		{"cache_server_requests_total", "Get requests received from peers.", func(g *Group) int64 { return g.Stats.ServerRequests.Get() }},
+		{"cache_sets_total", "Values written to the local cache by Set.", func(g *Group) int64 { return g.Stats.Sets.Get() }},
+		{"cache_removes_total", "Values removed from the local cache by Remove.", func(g *Group) int64 { return g.Stats.Removes.Get() }},
+		{"cache_loads_queued_total", "Getter loads that waited in the load queue.", func(g *Group) int64 { return g.Stats.LoadsQueued.Get() }},
+		{"cache_loads_rejected_total", "Getter loads rejected by LoadLimits.", func(g *Group) int64 { return g.Stats.LoadsRejected.Get() }},
+		{"cache_hedges_fired_total", "Hedged peer requests sent because the owner was slow.", func(g *Group) int64 { return g.Stats.HedgesFired.Get() }},
+		{"cache_hedge_wins_total", "Hedged peer requests that answered before the owner.", func(g *Group) int64 { return g.Stats.HedgeWins.Get() }},
+		{"cache_store_writes_total", "Writes and deletes committed to the backing store.", func(g *Group) int64 { return g.Stats.StoreWrites.Get() }},
+		{"cache_store_write_errors_total", "Writes and deletes the backing store failed to commit.", func(g *Group) int64 { return g.Stats.StoreWriteErrs.Get() }},
+		{"cache_writes_queued_total", "Writes queued for write-behind.", func(g *Group) int64 { return g.Stats.WritesQueued.Get() }},
+		{"cache_writes_coalesced_total", "Queued writes replaced by a later write to the same key.", func(g *Group) int64 { return g.Stats.WritesCoalesced.Get() }},
+		{"cache_write_retries_total", "Write-behind batch retries.", func(g *Group) int64 { return g.Stats.WriteRetries.Get() }},
+		{"cache_hot_key_mirrors_total", "Hot keys fetched from the owner and mirrored locally.", func(g *Group) int64 { return g.Stats.HotKeyMirrors.Get() }},
+		{"cache_stale_served_total", "Stale values returned because every load path failed.", func(g *Group) int64 { return g.Stats.StaleServed.Get() }},
+		{"cache_incrs_total", "Counter increments and decrements applied to the local cache.", func(g *Group) int64 { return g.Stats.Incrs.Get() }},
+		{"cache_evictions_total", "Entries evicted because the cache was full.", func(g *Group) int64 { return g.mainCache.evicted() }},
+		{"cache_disk_demotions_total", "Evicted entries written to the disk tier.", func(g *Group) int64 { return g.DiskStats().Puts }},
+		{"cache_disk_hits_total", "Entries read back from the disk tier.", func(g *Group) int64 { return g.DiskStats().Hits }},
+		{"cache_disk_evictions_total", "Entries dropped from the disk tier because it was full.", func(g *Group) int64 { return g.DiskStats().Evictions }},
+	}
+	for _, c := range counters {
+		writeHeader(w, c.name, c.help, "counter")
+		for _, g := range list {
+			fmt.Fprintf(w, "%s{group=%s} %d\n", c.name, quoteLabel(g.name), c.value(g))
+		}
+	}
+
+	gauges := []struct {
+		name, help string
+		value      func(bytes, cacheBytes int64, items int) int64
+	}{
+		{"cache_bytes", "Bytes used by the local cache.", func(bytes, _ int64, _ int) int64 { return bytes }},
+		{"cache_max_bytes", "Configured cacheBytes of the group, 0 means unlimited.", func(_, cacheBytes int64, _ int) int64 { return cacheBytes }},
+		{"cache_items", "Entries in the local cache.", func(_, _ int64, items int) int64 { return int64(items) }},
+	}
+	for _, c := range gauges {
+		writeHeader(w, c.name, c.help, "gauge")
+		for _, g := range list {
+			fmt.Fprintf(w, "%s{group=%s} %d\n", c.name, quoteLabel(g.name), c.value(g.mainCache.usage()))
+		}
+	}
+
+	writeHeader(w, "cache_load_duration_seconds", "Latency of Getter loads.", "histogram")
+	for _, g := range list {
+		writeHistogram(w, "cache_load_duration_seconds", g.name, &g.loadLatency)
+	}
+	writeHeader(w, "cache_peer_get_duration_seconds", "Latency of Get calls to peers.", "histogram")
+	for _, g := range list {
+		writeHistogram(w, "cache_peer_get_duration_seconds", g.name, &g.peerLatency)
+	}
+
+	if m.pool != nil {
+		peers, vnodes := m.pool.ringSize()
+		writeHeader(w, "cache_ring_peers", "Peers in the consistent hash ring.", "gauge")
+		fmt.Fprintf(w, "cache_ring_peers %d\n", peers)
+		writeHeader(w, "cache_ring_virtual_nodes", "Virtual nodes in the consistent hash ring.", "gauge")
+		fmt.Fprintf(w, "cache_ring_virtual_nodes %d\n", vnodes)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeHistogram(w *bufio.Writer, name, group string, h *histogram) {
+	label := quoteLabel(group)
+	var count int64
+	for i := range h.counts {
+		count += atomic.LoadInt64(&h.counts[i])
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{group=%s,le=\"%s\"} %d\n", name, label, le, count)
+	}
+	sum := float64(atomic.LoadInt64(&h.sum)) / float64(time.Second)
+	fmt.Fprintf(w, "%s_sum{group=%s} %s\n", name, label, strconv.FormatFloat(sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count{group=%s} %d\n", name, label, count)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel 按照 text format 的规则转义 label 的值
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvictReason 缓存项被移除的原因
+type EvictReason int
+
+const (
+	EvictCapacity    EvictReason = iota // 超出 cacheBytes 被 LRU 淘汰
+	EvictExpired                        // 已经过期
+	EvictRemoved                        // 通过 Remove 或者管理接口删除
+	EvictPurged                         // 整个 group 被清空
+	EvictInvalidated                    // 属于旧的 generation
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictRemoved:
+		return "removed"
+	case EvictPurged:
+		return "purged"
+	case EvictInvalidated:
+		return "invalidated"
+	}
+	return fmt.Sprintf("EvictReason(%d)", int(r))
+}
+
+// Observer 接收 Group 的生命周期事件，可以用来接入 tracing 或者审计
+// 回调在调用方的协程中同步执行，不会持有缓存的锁，实现需要是并发安全的
+type Observer interface {
+	OnGetHit(group, key string)
+	OnGetMiss(group, key string)
+	OnLoadStart(group, key string)
+	OnLoadEnd(group, key string, d time.Duration, err error)
+	// OnPeerPick key 被分配给了其他节点
+	OnPeerPick(group, key, peer string)
+	// OnPeerFetch 从其他节点获取的结果
+	OnPeerFetch(group, key, peer string, d time.Duration, err error)
+	OnEvict(group, key string, reason EvictReason)
+}
+
+// NoopObserver 什么都不做的 Observer，可以嵌入到结构体中只实现关心的方法
+type NoopObserver struct{}
+
+func (NoopObserver) OnGetHit(group, key string)                                      {}
+func (NoopObserver) OnGetMiss(group, key string)                                     {}
+func (NoopObserver) OnLoadStart(group, key string)                                   {}
+func (NoopObserver) OnLoadEnd(group, key string, d time.Duration, err error)         {}
+func (NoopObserver) OnPeerPick(group, key, peer string)                              {}
+func (NoopObserver) OnPeerFetch(group, key, peer string, d time.Duration, err error) {}
+func (NoopObserver) OnEvict(group, key string, reason EvictReason)                   {}
+
+var _ Observer = NoopObserver{}
+
+// peerName 返回 peer 的名字，实现了 fmt.Stringer 的 PeerGetter 使用 String()
+func peerName(peer PeerGetter) string {
+	if s, ok := peer.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", peer)
+}
@@ -1,6 +1,11 @@
 package cache
 
-import pb "cache/cachepb"
+import (
+	"context"
+	"io"
+
+	pb "cache/cachepb"
+)
 
 // PeerGetter 用于从对应 group 查找缓存值。PeerGetter 就对应于上述流程中的 HTTP 客户端。
 type PeerGetter interface {
@@ -11,3 +16,64 @@ type PeerGetter interface {
 type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
 }
+
+// PeerLister 可选接口，BumpGeneration 等需要通知所有节点的操作通过它得到节点列表
+type PeerLister interface {
+	// GetAll 返回除自己以外的所有节点
+	GetAll() []PeerGetter
+}
+
+// ContextPeerGetter 可选接口，支持取消的 Get，hedged request 用它取消落后的请求
+type ContextPeerGetter interface {
+	GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// StreamPeerGetter 可选接口，大的缓存值以流的方式传输，不需要一次性读取到内存中
+type StreamPeerGetter interface {
+	// GetStream 返回读取缓存值的 reader，out 中除 Value 以外的字段在返回时已经填充，
+	// 调用方读取完毕之后需要 Close
+	GetStream(ctx context.Context, in *pb.Request, out *pb.Response) (io.ReadCloser, error)
+}
+
+// WritePeerGetter 可选接口，Group.Set、Group.Remove 通过它修改 owner 上的缓存
+type WritePeerGetter interface {
+	// Set 写入对应节点的缓存
+	Set(in *pb.SetRequest) error
+	// Remove 删除对应节点的缓存
+	Remove(in *pb.Request) error
+}
+
+// GenerationPeerGetter 可选接口，BumpGeneration 时通知对应节点新的 generation，in.Key 不使用
+type GenerationPeerGetter interface {
+	SetGeneration(in *pb.Request) error
+}
+
+// TagPeerGetter 可选接口，InvalidateTag 时删除对应节点上带有 tag 的缓存，in.Key 为 tag
+type TagPeerGetter interface {
+	InvalidateTag(in *pb.Request) error
+}
+
+// RemovePeerGetter 可选接口，Remove 时同时返回 key 是否存在于对方的缓存中
+type RemovePeerGetter interface {
+	RemoveExisted(in *pb.Request) (bool, error)
+}
+
+// TouchPeerGetter 可选接口，只修改 owner 上缓存值的过期时间
+type TouchPeerGetter interface {
+	Touch(in *pb.TouchRequest, out *pb.TouchResponse) error
+}
+
+// CASPeerGetter 可选接口，在 owner 上执行 CompareAndSet，版本不一致时 out.Swapped 为 false
+type CASPeerGetter interface {
+	CompareAndSet(in *pb.CasRequest, out *pb.CasResponse) error
+}
+
+// CounterPeerGetter 可选接口，在 owner 上执行 Incr、Decr
+type CounterPeerGetter interface {
+	Incr(in *pb.IncrRequest, out *pb.IncrResponse) error
+}
+
+// ReplicaPicker 可选接口，按照哈希环的顺序返回 key 对应的至多 n 个节点，不包括自己
+type ReplicaPicker interface {
+	PickReplicas(key string, n int) []PeerGetter
+}
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"cache/resp"
+)
+
+/*
+  RedisServer 使用 RESP2 协议对外提供服务，key 的格式为 {groupName}:{key}
+  支持的命令: GET SET DEL MGET EXISTS TTL PING INFO
+*/
+
+const redisVersion = "6.0.0" // 部分客户端会根据 INFO 中的版本号决定使用哪些命令
+
+// RedisServer 兼容 redis 协议的前端
+type RedisServer struct {
+	*tcpServer
+
+	// DefaultGroup 不为空时，不包含 ':' 的 key 使用这个 group
+	DefaultGroup string
+	// Registry 为 nil 时使用 DefaultRegistry
+	Registry *Registry
+}
+
+func NewRedisServer() *RedisServer {
+	return &RedisServer{tcpServer: newTCPServer()}
+}
+
+// ListenAndServe 监听 addr 并处理连接
+func (s *RedisServer) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理 l 上的连接，直到 Close 被调用
+func (s *RedisServer) Serve(l net.Listener) error {
+	return s.serve(l, s.serveConn)
+}
+
+func (s *RedisServer) serveConn(conn net.Conn) {
+	r, w := resp.NewReader(conn), resp.NewWriter(conn)
+	for {
+		args, err := r.ReadCommand()
+		if errors.Is(err, resp.ErrProtocol) {
+			// 与 redis 一样回复错误之后关闭连接
+			w.WriteError("ERR Protocol error")
+			w.Flush()
+			return
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Println("[RedisServer] read command:", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := s.exec(w, args)
+		// pipeline 中的命令全部处理完之后再一起发送
+		if r.Buffered() == 0 || quit {
+			if err = w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// exec 执行一条命令，返回 true 表示需要关闭连接
+func (s *RedisServer) exec(w *resp.Writer, args [][]byte) bool {
+	cmd := strings.ToUpper(string(args[0]))
+	args = args[1:]
+	switch cmd {
+	case "PING":
+		if len(args) > 0 {
+			w.WriteBulk(args[0])
+		} else {
+			w.WriteSimple("PONG")
+		}
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(w, cmd)
+		}
+		s.get(w, args[0])
+	case "MGET":
+		if len(args) == 0 {
+			return wrongArgs(w, cmd)
+		}
+		w.WriteArray(len(args))
+		for _, arg := range args {
+			s.mget(w, arg)
+		}
+	case "SET":
+		s.set(w, args)
+	case "DEL":
+		if len(args) == 0 {
+			return wrongArgs(w, cmd)
+		}
+		var n int64
+		for _, arg := range args {
+			if group, key, err := s.lookup(arg); err == nil {
+				// 只统计确实存在的 key
+				if existed, err := group.Remove(key); err == nil && existed {
+					n++
+				}
+			}
+		}
+		w.WriteInt(n)
+	case "EXISTS":
+		if len(args) == 0 {
+			return wrongArgs(w, cmd)
+		}
+		// 与 GET 一致，缓存未命中时会通过 Getter 加载
+		var n int64
+		for _, arg := range args {
+			if group, key, err := s.lookup(arg); err == nil {
+				if _, err = group.Get(key); err == nil {
+					n++
+				}
+			}
+		}
+		w.WriteInt(n)
+	case "TTL":
+		if len(args) != 1 {
+			return wrongArgs(w, cmd)
+		}
+		s.ttl(w, args[0])
+	case "INFO":
+		w.WriteBulk([]byte(s.info()))
+	case "COMMAND":
+		// redis-cli 启动时会发送 COMMAND DOCS
+		w.WriteArray(0)
+	case "QUIT":
+		w.WriteSimple("OK")
+		return true
+	default:
+		w.WriteError(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(cmd)))
+	}
+	return false
+}
+
+func wrongArgs(w *resp.Writer, cmd string) bool {
+	w.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+	return false
+}
+
+// lookup 将 {groupName}:{key} 解析为 group 和 key
+func (s *RedisServer) lookup(arg []byte) (*Group, string, error) {
+	group, key, err := lookupGroup(s.Registry, s.DefaultGroup, string(arg))
+	if err != nil {
+		return nil, "", errors.New("ERR " + err.Error())
+	}
+	return group, key, nil
+}
+
+func (s *RedisServer) get(w *resp.Writer, arg []byte) {
+	group, key, err := s.lookup(arg)
+	if err != nil {
+		w.WriteError(err.Error())
+		return
+	}
+	view, err := group.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		w.WriteNull()
+		return
+	}
+	if err != nil {
+		w.WriteError("ERR " + err.Error())
+		return
+	}
+	w.WriteBulk(view.ByteSlice())
+}
+
+// mget 与 redis 一样，数组中不存在或者读取失败的 key 都是 nil，不会出现错误
+func (s *RedisServer) mget(w *resp.Writer, arg []byte) {
+	group, key, err := s.lookup(arg)
+	if err != nil {
+		w.WriteNull()
+		return
+	}
+	view, err := group.Get(key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("[RedisServer] mget %s: %v", arg, err)
+		}
+		w.WriteNull()
+		return
+	}
+	w.WriteBulk(view.ByteSlice())
+}
+
+// set SET key value [EX seconds|PX milliseconds]
+func (s *RedisServer) set(w *resp.Writer, args [][]byte) {
+	if len(args) != 2 && len(args) != 4 {
+		wrongArgs(w, "SET")
+		return
+	}
+	group, key, err := s.lookup(args[0])
+	if err != nil {
+		w.WriteError(err.Error())
+		return
+	}
+	var expire time.Time
+	if len(args) == 4 {
+		n, err := strconv.ParseInt(string(args[3]), 10, 64)
+		if err != nil || n <= 0 {
+			w.WriteError("ERR invalid expire time in 'set' command")
+			return
+		}
+		switch strings.ToUpper(string(args[2])) {
+		case "EX":
+			expire = time.Now().Add(time.Duration(n) * time.Second)
+		case "PX":
+			expire = time.Now().Add(time.Duration(n) * time.Millisecond)
+		default:
+			w.WriteError("ERR syntax error")
+			return
+		}
+	}
+	if err = group.Set(key, args[1], expire); err != nil {
+		w.WriteError("ERR " + err.Error())
+		return
+	}
+	w.WriteSimple("OK")
+}
+
+// ttl 返回剩余的秒数，-1 表示永不过期，-2 表示 key 不存在
+func (s *RedisServer) ttl(w *resp.Writer, arg []byte) {
+	group, key, err := s.lookup(arg)
+	if err != nil {
+		w.WriteError(err.Error())
+		return
+	}
+	view, err := group.Get(key)
+	switch {
+	case err != nil:
+		w.WriteInt(-2)
+	case view.Expire().IsZero():
+		w.WriteInt(-1)
+	default:
+		w.WriteInt(int64((time.Until(view.Expire()) + time.Second - 1) / time.Second))
+	}
+}
+
+func (s *RedisServer) info() string {
+	clients, uptime := s.status()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Server\r\nredis_version:%s\r\ngo_version:%s\r\nuptime_in_seconds:%d\r\n\r\n",
+		redisVersion, runtime.Version(), int64(uptime/time.Second))
+	fmt.Fprintf(&b, "# Clients\r\nconnected_clients:%d\r\n\r\n", clients)
+
+	list := s.Registry.orDefault().Groups()
+	var hits, misses int64
+	for _, g := range list {
+		hits += g.Stats.CacheHits.Get()
+		misses += g.Stats.Loads.Get()
+	}
+	fmt.Fprintf(&b, "# Stats\r\nkeyspace_hits:%d\r\nkeyspace_misses:%d\r\n\r\n", hits, misses)
+
+	b.WriteString("# Keyspace\r\n")
+	for _, g := range list {
+		bytes, cacheBytes, items := g.mainCache.usage()
+		fmt.Fprintf(&b, "%s:keys=%d,bytes=%d,cache_bytes=%d,gets=%d,hits=%d,loads=%d,peer_loads=%d,peer_errors=%d\r\n",
+			g.name, items, bytes, cacheBytes, g.Stats.Gets.Get(), g.Stats.CacheHits.Get(),
+			g.Stats.Loads.Get(), g.Stats.PeerLoads.Get(), g.Stats.PeerErrors.Get())
+	}
+	return b.String()
+}
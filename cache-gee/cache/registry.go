@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrGroupExists NewGroup 时同名的 group 已经存在
+var ErrGroupExists = errors.New("group already exists")
+
+// Registry group 的命名空间，不同的 Registry 之间互相隔离
+// 包级别的 NewGroup、GetGroup 等函数使用 DefaultRegistry
+type Registry struct {
+	mtx    sync.RWMutex      // 读写锁
+	groups map[string]*Group // key:groupName,value:Group
+
+	budgetMtx sync.Mutex
+	budget    *budgeter // 为 nil 时每个 group 使用自己的 cacheBytes
+}
+
+// DefaultRegistry 默认的 Registry
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{groups: make(map[string]*Group)}
+}
+
+// orDefault r 为 nil 时返回 DefaultRegistry
+func (r *Registry) orDefault() *Registry {
+	if r == nil {
+		return DefaultRegistry
+	}
+	return r
+}
+
+// NewGroup 创建一个新的Group，同名的 group 已经存在时返回 ErrGroupExists
+func (r *Registry) NewGroup(name string, cacheBytes int64, getter Getter) (*Group, error) {
+	g := newGroup(name, cacheBytes, getter)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.groups[name]; ok {
+		return nil, ErrGroupExists
+	}
+	r.groups[name] = g
+	return g, nil
+}
+
+// ReplaceGroup 创建一个新的Group，替换掉同名的 group，旧 group 的缓存会被清空
+func (r *Registry) ReplaceGroup(name string, cacheBytes int64, getter Getter) *Group {
+	g := newGroup(name, cacheBytes, getter)
+	r.mtx.Lock()
+	old := r.groups[name]
+	r.groups[name] = g
+	r.mtx.Unlock()
+
+	if old != nil {
+		old.SetWriteBehind(nil)
+		old.mainCache.clear()
+		old.mainCache.close()
+	}
+	return g
+}
+
+// GetGroup returns the named group previously created with NewGroup, or
+// nil if there's no such group.
+func (r *Registry) GetGroup(name string) *Group {
+	r.mtx.RLock()
+	group := r.groups[name]
+	r.mtx.RUnlock()
+	return group
+}
+
+// DeleteGroup 删除 group 并清空它的缓存，group 不存在时返回 false
+// 已经持有 *Group 的调用方仍然可以继续使用它
+func (r *Registry) DeleteGroup(name string) bool {
+	r.mtx.Lock()
+	g, ok := r.groups[name]
+	delete(r.groups, name)
+	r.mtx.Unlock()
+
+	if ok {
+		g.SetWriteBehind(nil)
+		g.mainCache.clear()
+		g.mainCache.close()
+	}
+	return ok
+}
+
+// Groups 返回按名字排序的所有 group
+func (r *Registry) Groups() []*Group {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	list := make([]*Group, 0, len(r.groups))
+	for _, g := range r.groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
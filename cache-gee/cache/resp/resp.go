@@ -0,0 +1,227 @@
+// Package resp 实现了 RESP2(REdis Serialization Protocol) 的编码和解码
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	maxBulkLen      = 512 << 20 // 与 redis 的 proto-max-bulk-len 一致
+	maxMultiBulkLen = 1024 * 1024
+	// bulkChunk 按照它的大小逐步读取 bulk，不会因为对方声明的长度一次分配大块内存
+	bulkChunk = 64 << 10
+	// maxPrealloc 预先分配的数组长度上限，之后随着读取的元素增长
+	maxPrealloc = 64
+)
+
+var ErrProtocol = errors.New("resp: protocol error")
+
+// Error 服务端返回的 -ERR 错误
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Reader 读取客户端发送的命令或者服务端返回的回复
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Buffered 缓冲区中还未读取的字节数，可以用来判断客户端是否还有 pipeline 中的命令
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// ReadCommand 读取一条命令，支持 multi bulk 以及 inline 两种格式
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		// inline command, eg: PING\r\n
+		return bytes.Fields(line), nil
+	}
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > maxMultiBulkLen {
+		return nil, ErrProtocol
+	}
+	args := make([][]byte, 0, prealloc(n))
+	for i := 0; i < n; i++ {
+		line, err = r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, ErrProtocol
+		}
+		arg, err := r.readBulk(line)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// ReadReply 读取一条回复，返回值的类型为:
+// string(simple string), Error, int64, []byte(bulk string), nil(null), []interface{}(array)
+func (r *Reader) ReadReply() (interface{}, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, ErrProtocol
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		b, err := r.readBulk(line)
+		if b == nil || err != nil {
+			return nil, err
+		}
+		return b, nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxMultiBulkLen {
+			return nil, ErrProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]interface{}, 0, prealloc(n))
+		for i := 0; i < n; i++ {
+			v, err := r.ReadReply()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}
+	return nil, ErrProtocol
+}
+
+// readBulk 读取 $len 之后的内容，$-1 返回 nil
+func (r *Reader) readBulk(line []byte) ([]byte, error) {
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > maxBulkLen {
+		return nil, ErrProtocol
+	}
+	if n < 0 {
+		return nil, nil
+	}
+	size := n + 2
+	if size > bulkChunk {
+		size = bulkChunk
+	}
+	// 数据到达之后才继续分配，声明的长度再大也只占用已经收到的内存
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err = io.CopyN(buf, r.r, int64(n+2)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	b := buf.Bytes()
+	if b[n] != '\r' || b[n+1] != '\n' {
+		return nil, ErrProtocol
+	}
+	return b[:n], nil
+}
+
+func prealloc(n int) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+	return n
+}
+
+// readLine 读取一行，不包括结尾的 \r\n
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, ErrProtocol
+	}
+	if err != nil {
+		return nil, err
+	}
+	line = bytes.TrimRight(line, "\r\n")
+	return append([]byte(nil), line...), nil
+}
+
+// Writer 写入命令或者回复，调用 Flush 之后才会真正发送
+type Writer struct {
+	w *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) WriteSimple(s string) error {
+	_, err := fmt.Fprintf(w.w, "+%s\r\n", s)
+	return err
+}
+
+func (w *Writer) WriteError(s string) error {
+	_, err := fmt.Fprintf(w.w, "-%s\r\n", s)
+	return err
+}
+
+func (w *Writer) WriteInt(n int64) error {
+	_, err := fmt.Fprintf(w.w, ":%d\r\n", n)
+	return err
+}
+
+func (w *Writer) WriteBulk(b []byte) error {
+	if _, err := fmt.Fprintf(w.w, "$%d\r\n", len(b)); err != nil {
+		return err
+	}
+	w.w.Write(b)
+	_, err := w.w.WriteString("\r\n")
+	return err
+}
+
+// WriteNull 写入 $-1，表示 key 不存在
+func (w *Writer) WriteNull() error {
+	_, err := w.w.WriteString("$-1\r\n")
+	return err
+}
+
+// WriteArray 写入数组的长度，之后需要再写入 n 个元素
+func (w *Writer) WriteArray(n int) error {
+	_, err := fmt.Fprintf(w.w, "*%d\r\n", n)
+	return err
+}
+
+// WriteCommand 以 multi bulk 的格式写入一条命令
+func (w *Writer) WriteCommand(args ...string) error {
+	w.WriteArray(len(args))
+	for _, arg := range args {
+		if err := w.WriteBulk([]byte(arg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
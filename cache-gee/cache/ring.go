@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"cache/consistenthash"
+)
+
+/*
+  ring api:
+  GET {path}                          哈希环上每个节点的虚拟节点以及负责的哈希空间
+  GET {path}?key={key}&n=3            key 所属的节点以及按照哈希环顺序的 n 个副本节点
+  GET {path}?add={peer}&remove={peer} 模拟增加、删除节点之后需要迁移的 key 的比例，可以重复
+*/
+
+const defaultRingReplicas = 3
+
+// RingInfo 哈希环的布局
+type RingInfo struct {
+	Self     string                `json:"self"`
+	Replicas int                   `json:"replicas"` // 每个节点的虚拟节点数量
+	VNodes   int                   `json:"vnodes"`
+	Peers    []consistenthash.Node `json:"peers"`
+}
+
+// KeyOwner key 所属的节点，Replicas 的第一个就是 Owner
+type KeyOwner struct {
+	Key      string   `json:"key"`
+	Owner    string   `json:"owner"`
+	Replicas []string `json:"replicas"`
+}
+
+// RingChange 模拟修改节点的结果
+type RingChange struct {
+	Add    []string              `json:"add,omitempty"`
+	Remove []string              `json:"remove,omitempty"`
+	Moved  float64               `json:"moved"` // 需要迁移的 key 的比例
+	Peers  []consistenthash.Node `json:"peers"` // 修改之后的布局
+}
+
+// Ring 返回当前哈希环的布局
+func (h *HttpPool) Ring() RingInfo {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	info := RingInfo{Self: h.self, Replicas: h.opts.Replicas, Peers: []consistenthash.Node{}}
+	if h.peers != nil {
+		info.VNodes = h.peers.Len()
+		info.Peers = h.peers.Nodes()
+	}
+	return info
+}
+
+// Locate 返回 key 所属的节点以及至多 n 个副本节点，包括自己
+func (h *HttpPool) Locate(key string, n int) KeyOwner {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	owner := KeyOwner{Key: key, Replicas: []string{}}
+	if h.peers != nil {
+		owner.Owner = h.peers.Get(key)
+		if replicas := h.peers.GetN(key, n); replicas != nil {
+			owner.Replicas = replicas
+		}
+	}
+	return owner
+}
+
+// SimulateChange 计算增加 add、删除 remove 之后需要迁移的 key 的比例，不会修改当前的哈希环
+func (h *HttpPool) SimulateChange(add, remove []string) (RingChange, error) {
+	h.mtx.Lock()
+	peers := make(map[string]bool, len(h.httpGetters))
+	for addr := range h.httpGetters {
+		peers[addr] = true
+	}
+	current := h.peers
+	h.mtx.Unlock()
+
+	for _, addr := range remove {
+		if !peers[addr] {
+			return RingChange{}, fmt.Errorf("no such peer: %s", addr)
+		}
+		delete(peers, addr)
+	}
+	for _, addr := range add {
+		if peers[addr] {
+			return RingChange{}, fmt.Errorf("peer already exists: %s", addr)
+		}
+		peers[addr] = true
+	}
+	if current == nil {
+		current = consistenthash.New(h.opts.Replicas, h.opts.HashFn)
+	}
+
+	addrs := make([]string, 0, len(peers))
+	for addr := range peers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	next := consistenthash.New(h.opts.Replicas, h.opts.HashFn)
+	next.Add(addrs...)
+	return RingChange{
+		Add:    add,
+		Remove: remove,
+		Moved:  current.Moved(next),
+		Peers:  next.Nodes(),
+	}, nil
+}
+
+// RingHandler 返回查看哈希环的 http.Handler，不校验节点之间的签名，应当只挂载到内部使用的端口，
+// 例如 mux.Handle("/_cache_ring", pool.RingHandler())
+func (h *HttpPool) RingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case query.Get("key") != "":
+			n, _ := strconv.Atoi(query.Get("n"))
+			if n <= 0 {
+				n = defaultRingReplicas
+			}
+			writeJSON(w, h.Locate(query.Get("key"), n))
+		case len(query["add"]) > 0 || len(query["remove"]) > 0:
+			change, err := h.SimulateChange(query["add"], query["remove"])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, change)
+		default:
+			writeJSON(w, h.Ring())
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	headerTimestamp     = "X-Cache-Timestamp"
+	headerNonce         = "X-Cache-Nonce"
+	headerSignature     = "X-Cache-Signature"
+	defaultMaxClockSkew = 30 * time.Second
+	defaultMaxBodyBytes = 64 << 20
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// TLSOptions 节点之间通信使用的 TLS 配置
+type TLSOptions struct {
+	Certificate tls.Certificate // 本节点证书，服务端使用，开启 Mutual 时也作为客户端证书
+	RootCAs     *x509.CertPool  // 用来校验对端证书的 CA
+	Mutual      bool            // 双向认证：要求对端出示由 RootCAs 签发的证书
+	ServerName  string          // 可选，覆盖校验服务端证书时使用的主机名
+}
+
+// LoadTLSOptions 从 PEM 文件中加载证书、私钥以及 CA
+func LoadTLSOptions(certFile, keyFile, caFile string, mutual bool) (*TLSOptions, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading key pair: %v", err)
+	}
+	caPEM, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading ca file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return &TLSOptions{Certificate: cert, RootCAs: pool, Mutual: mutual}, nil
+}
+
+// ServerConfig 返回给 http.Server 使用的 tls.Config
+// 开启 Mutual 时没有出示证书的请求可以完成握手，但会被 HttpPool 以 401 拒绝
+func (o *TLSOptions) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{o.Certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if o.Mutual {
+		cfg.ClientCAs = o.RootCAs
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg
+}
+
+// ClientConfig 返回 httpGetter 访问其他节点时使用的 tls.Config
+func (o *TLSOptions) ClientConfig() *tls.Config {
+	cfg := &tls.Config{
+		RootCAs:    o.RootCAs,
+		ServerName: o.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if o.Mutual {
+		cfg.Certificates = []tls.Certificate{o.Certificate}
+	}
+	return cfg
+}
+
+// signer 使用共享密钥对节点间请求做 HMAC-SHA256 签名
+// 签名覆盖 method、uri、body、时间戳以及随机 nonce，
+// 超出 maxSkew 的时间戳和重复出现的 nonce 都会被拒绝，以此防止重放。
+type signer struct {
+	secret  []byte
+	maxSkew time.Duration
+	maxBody int64 // 校验签名之前需要读取整个请求体，超出时直接拒绝
+
+	mtx       sync.Mutex
+	seen      map[string]struct{} // 当前周期内出现过的 nonce
+	prev      map[string]struct{} // 上一个周期内出现过的 nonce
+	rotatedAt time.Time
+}
+
+func newSigner(secret []byte, maxSkew time.Duration, maxBody int64) *signer {
+	if maxSkew <= 0 {
+		maxSkew = defaultMaxClockSkew
+	}
+	if maxBody <= 0 {
+		maxBody = defaultMaxBodyBytes
+	}
+	return &signer{
+		secret:    secret,
+		maxSkew:   maxSkew,
+		maxBody:   maxBody,
+		seen:      make(map[string]struct{}),
+		prev:      make(map[string]struct{}),
+		rotatedAt: time.Now(),
+	}
+}
+
+func (s *signer) mac(method, uri, ts, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	m := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(m, "%s\n%s\n%s\n%s\n%x", method, uri, ts, nonce, sum)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// sign 为请求添加签名相关的 header，body 为请求体(可以为 nil)
+func (s *signer) sign(r *http.Request, body []byte) error {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(raw[:])
+	r.Header.Set(headerTimestamp, ts)
+	r.Header.Set(headerNonce, nonce)
+	r.Header.Set(headerSignature, s.mac(r.Method, r.URL.RequestURI(), ts, nonce, body))
+	return nil
+}
+
+// verify 校验请求的签名，校验通过后 r.Body 仍然可以被读取
+// 时间戳在读取请求体之前检查，请求体最多读取 maxBody
+func (s *signer) verify(r *http.Request) error {
+	ts, nonce, sig := r.Header.Get(headerTimestamp), r.Header.Get(headerNonce), r.Header.Get(headerSignature)
+	if ts == "" || nonce == "" || sig == "" {
+		return errors.New("missing signature")
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("bad timestamp")
+	}
+	now := time.Now()
+	if d := now.Sub(time.Unix(unix, 0)); d > s.maxSkew || d < -s.maxSkew {
+		return errors.New("timestamp out of range")
+	}
+
+	if r.ContentLength > s.maxBody {
+		return errBodyTooLarge
+	}
+	var body []byte
+	if r.Body != nil {
+		if body, err = ioutil.ReadAll(io.LimitReader(r.Body, s.maxBody+1)); err != nil {
+			return err
+		}
+		if int64(len(body)) > s.maxBody {
+			return errBodyTooLarge
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	expect := s.mac(r.Method, r.URL.RequestURI(), ts, nonce, body)
+	if !hmac.Equal([]byte(expect), []byte(sig)) {
+		return errors.New("bad signature")
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	// 时间戳的有效窗口是 2*maxSkew，两个周期的 nonce 足以覆盖
+	if now.Sub(s.rotatedAt) > 2*s.maxSkew {
+		s.prev, s.seen = s.seen, make(map[string]struct{})
+		s.rotatedAt = now
+	}
+	if _, ok := s.seen[nonce]; ok {
+		return errors.New("replayed request")
+	}
+	if _, ok := s.prev[nonce]; ok {
+		return errors.New("replayed request")
+	}
+	s.seen[nonce] = struct{}{}
+	return nil
+}
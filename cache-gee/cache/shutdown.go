@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	pb "cache/cachepb"
+	"cache/consistenthash"
+)
+
+// Shutdown 让当前节点平滑下线：
+//  1. HandOffHotKeys 为 true 时，把自己负责的热点 key 写入它们新的 owner，只写入缓存，不写入数据源
+//  2. 通知其他节点把自己从哈希环中删除，之后它们不再把 key 转发过来
+//  3. 之后其他节点的请求返回 503，等待正在处理的请求以及所有 group 中正在进行的加载结束
+//
+// 通知失败的节点在请求失败之后仍然会从本地加载，Shutdown 返回第一个通知失败的错误；
+// ctx 结束时返回 ctx.Err()。节点重新启动之后，其他节点需要再次调用 Set 才会把它加入哈希环
+func (h *HttpPool) Shutdown(ctx context.Context) error {
+	h.mtx.Lock()
+	ring, addrs, getters := h.peers, h.addrs, h.httpGetters
+	h.mtx.Unlock()
+
+	if h.opts.HandOffHotKeys && ring != nil {
+		h.handOff(ctx, ring, addrs, getters)
+	}
+
+	var notifyErr error
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for addr, getter := range getters {
+		if addr == h.self {
+			continue
+		}
+		wg.Add(1)
+		go func(addr string, getter *httpGetter) {
+			defer wg.Done()
+			if err := getter.leave(ctx, h.self); err != nil {
+				h.Log("notify %s of leaving: %v", addr, err)
+				mtx.Lock()
+				if notifyErr == nil {
+					notifyErr = err
+				}
+				mtx.Unlock()
+			}
+		}(addr, getter)
+	}
+	wg.Wait()
+
+	if err := h.drain(ctx); err != nil {
+		return err
+	}
+	for _, g := range h.opts.Registry.orDefault().Groups() {
+		if err := g.waitLoads(ctx); err != nil {
+			return err
+		}
+	}
+	return notifyErr
+}
+
+// handOff 把自己负责的热点 key 写入去掉自己之后的哈希环中的 owner
+func (h *HttpPool) handOff(ctx context.Context, ring *consistenthash.Map, addrs []string, getters map[string]*httpGetter) {
+	next := consistenthash.New(h.opts.Replicas, h.opts.HashFn)
+	for _, addr := range addrs {
+		if addr != h.self {
+			next.Add(addr)
+		}
+	}
+	for _, g := range h.opts.Registry.orDefault().Groups() {
+		n := 0
+		for key, e := range g.hotEntries() {
+			if ctx.Err() != nil {
+				return
+			}
+			if ring.Get(key) != h.self {
+				continue
+			}
+			owner := next.Get(key)
+			if owner == "" {
+				continue
+			}
+			err := getters[owner].SetContext(ctx, &pb.SetRequest{
+				Group:           g.name,
+				Key:             key,
+				Value:           e.Value,
+				Expire:          unixNano(e.Expire),
+				Tags:            e.Tags,
+				Version:         e.Version,
+				ContentType:     e.ContentType,
+				ContentEncoding: e.ContentEncoding,
+				Flags:           e.Flags,
+				Fill:            true,
+			})
+			if err != nil {
+				h.Log("hand off %s/%s to %s: %v", g.name, key, owner, err)
+				continue
+			}
+			n++
+		}
+		if n > 0 {
+			h.Log("handed off %d hot keys of %s", n, g.name)
+		}
+	}
+}
+
+// handlerLeave 其他节点下线时把它从哈希环中删除
+func (h *HttpPool) handlerLeave(w http.ResponseWriter, r *http.Request) {
+	in := &pb.Request{}
+	if !readProto(w, r, in) {
+		return
+	}
+	if h.removePeer(in.GetKey()) {
+		h.Log("peer %s left", in.GetKey())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// begin 开始处理一个请求，Shutdown 之后返回 false
+func (h *HttpPool) begin() bool {
+	h.drainMtx.RLock()
+	defer h.drainMtx.RUnlock()
+	if h.draining {
+		return false
+	}
+	h.active.Add(1)
+	return true
+}
+
+// drain 拒绝新的请求，等待正在处理的请求结束
+func (h *HttpPool) drain(ctx context.Context) error {
+	h.drainMtx.Lock()
+	h.draining = true
+	h.drainMtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitLoads 等待正在进行的加载结束
+func (g *Group) waitLoads(ctx context.Context) error {
+	if err := g.loader.Wait(ctx); err != nil {
+		return err
+	}
+	return g.localLoader.Wait(ctx)
+}
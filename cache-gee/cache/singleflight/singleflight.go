@@ -1,6 +1,9 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // call 正在进行或者已经结束的请求
 type call struct {
@@ -42,3 +45,31 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 
 	return c.val, c.err // 返回结果
 }
+
+// Wait 等待调用时正在进行的请求结束，之后发起的请求不会等待
+// ctx 结束时返回 ctx.Err()
+func (g *Group) Wait(ctx context.Context) error {
+	g.mtx.Lock()
+	calls := make([]*call, 0, len(g.m))
+	for _, c := range g.m {
+		calls = append(calls, c)
+	}
+	g.mtx.Unlock()
+	if len(calls) == 0 {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, c := range calls {
+			c.wg.Wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
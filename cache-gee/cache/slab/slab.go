@@ -0,0 +1,233 @@
+// Package slab 把大量小的缓存值拷贝到预先分配的大块内存中，减少堆上的对象数量
+package slab
+
+import "sort"
+
+const (
+	defaultSlabSize     = 1 << 20
+	defaultMinItem      = 64
+	defaultGrowthFactor = 1.25
+	align               = 8
+)
+
+// Options Arena 的配置
+type Options struct {
+	SlabSize     int     // 每次向堆申请的内存大小，默认 1MB
+	MinItem      int     // 最小的 size class，默认 64 bytes
+	GrowthFactor float64 // 相邻 size class 之间的倍数，默认 1.25
+}
+
+// Ref 指向 Arena 中的一段空间，不包含指针，零值表示无效
+type Ref struct {
+	slab  uint32 // slabs 的下标加一
+	off   uint32
+	n     uint32 // 写入的长度
+	size  uint32 // 实际占用的长度，即 size class 的大小
+	class uint16
+}
+
+// Len 返回写入的长度
+func (r Ref) Len() int {
+	return int(r.n)
+}
+
+// Cap 返回实际占用的长度，不小于 Len
+func (r Ref) Cap() int {
+	return int(r.size)
+}
+
+// Valid 是否指向 Arena 中的空间
+func (r Ref) Valid() bool {
+	return r.slab != 0
+}
+
+// class 一个 size class，每个 slab 只属于一个 class，被切分成相同大小的 item
+type class struct {
+	size int
+	free []uint64 // 被释放的 item，slab<<32 | off
+	cur  uint32   // 正在切分的 slab，0 表示没有
+	next int      // cur 中下一个未使用的 item 的偏移
+}
+
+// Arena 按照 size class 管理的内存，不是并发安全的
+// 被释放的 item 只会在同一个 class 中复用；slab 中的 item 全部被释放之后归还给堆，
+// 正在切分的 slab 除外。
+// Share 过的 slab 中被释放的 item 不再复用，归还之后由 GC 在没有引用时回收，
+// 所以 Share 返回的 []byte 在 Free 以及 Reset 之后仍然有效并且不会被修改
+type Arena struct {
+	slabSize int
+	classes  []class
+	slabs    [][]byte // 归还的 slab 为 nil，下标留给之后申请的 slab
+	live     []int    // 每个 slab 中已分配的 item 数量
+	shared   []bool   // slab 中的 item 是否被 Share 过
+	dead     []int64  // Share 过的 slab 中被释放、不能复用的空间
+	spare    []uint32 // 已经归还的 slab 的下标加一
+	count    int      // 没有归还的 slab 的数量
+	used     int64    // 已分配的 item 占用的空间
+	stranded int64    // 除了正在切分的 slab 以外，所有 slab 的 dead 之和
+}
+
+// Stats Arena 的内存使用情况
+type Stats struct {
+	Slabs     int   // slab 的数量
+	SlabBytes int64 // 向堆申请的内存
+	UsedBytes int64 // 已分配的 item 占用的内存，包括 size class 取整的部分，SlabBytes 中其余的部分是空闲的
+	// StrandedBytes 空闲的部分中因为 Share 不能复用的内存，所在的 slab 归还之后才会释放；
+	// 不包括正在切分的 slab，它们的数量不超过 size class 的数量
+	StrandedBytes int64
+}
+
+func New(opts Options) *Arena {
+	if opts.SlabSize <= 0 {
+		opts.SlabSize = defaultSlabSize
+	}
+	if opts.MinItem <= 0 {
+		opts.MinItem = defaultMinItem
+	}
+	if opts.GrowthFactor <= 1 {
+		opts.GrowthFactor = defaultGrowthFactor
+	}
+
+	// 每个 slab 至少能放下 4 个 item
+	a := &Arena{slabSize: opts.SlabSize}
+	maxItem := opts.SlabSize / 4
+	for size := roundUp(opts.MinItem); size <= maxItem; {
+		a.classes = append(a.classes, class{size: size})
+		next := roundUp(int(float64(size) * opts.GrowthFactor))
+		if next == size {
+			next += align
+		}
+		size = next
+	}
+	return a
+}
+
+func roundUp(n int) int {
+	return (n + align - 1) / align * align
+}
+
+// MaxItem 返回可以分配的最大长度
+func (a *Arena) MaxItem() int {
+	if len(a.classes) == 0 {
+		return 0
+	}
+	return a.classes[len(a.classes)-1].size
+}
+
+// Alloc 分配至少 n bytes 的空间，n 为 0 或者超过 MaxItem 时返回 false
+func (a *Arena) Alloc(n int) (Ref, bool) {
+	if n <= 0 || n > a.MaxItem() {
+		return Ref{}, false
+	}
+	i := sort.Search(len(a.classes), func(i int) bool { return a.classes[i].size >= n })
+	c := &a.classes[i]
+	a.used += int64(c.size)
+
+	if last := len(c.free) - 1; last >= 0 {
+		p := c.free[last]
+		c.free = c.free[:last]
+		a.live[p>>32-1]++
+		return Ref{slab: uint32(p >> 32), off: uint32(p), n: uint32(n), size: uint32(c.size), class: uint16(i)}, true
+	}
+	if c.cur == 0 || c.next+c.size > a.slabSize {
+		if c.cur != 0 {
+			a.stranded += a.dead[c.cur-1]
+			if a.live[c.cur-1] == 0 {
+				// Share 过的 slab 中的 item 全部被释放时没有归还，不再切分之后归还
+				a.release(c, c.cur)
+			}
+		}
+		c.cur, c.next = a.newSlab(), 0
+	}
+	r := Ref{slab: c.cur, off: uint32(c.next), n: uint32(n), size: uint32(c.size), class: uint16(i)}
+	c.next += c.size
+	a.live[c.cur-1]++
+	return r, true
+}
+
+// newSlab 向堆申请一个 slab，优先使用已经归还的下标，返回下标加一
+func (a *Arena) newSlab() uint32 {
+	a.count++
+	if last := len(a.spare) - 1; last >= 0 {
+		id := a.spare[last]
+		a.spare = a.spare[:last]
+		a.slabs[id-1] = make([]byte, a.slabSize)
+		a.shared[id-1] = false
+		return id
+	}
+	a.slabs = append(a.slabs, make([]byte, a.slabSize))
+	a.live = append(a.live, 0)
+	a.shared = append(a.shared, false)
+	a.dead = append(a.dead, 0)
+	return uint32(len(a.slabs))
+}
+
+// Free 释放 r，之后 r 指向的空间可能被其他 Alloc 复用，Share 过的 slab 除外
+func (a *Arena) Free(r Ref) {
+	if !r.Valid() {
+		return
+	}
+	c := &a.classes[r.class]
+	a.used -= int64(c.size)
+	if a.live[r.slab-1]--; a.live[r.slab-1] == 0 && r.slab != c.cur {
+		a.release(c, r.slab)
+		return
+	}
+	if a.shared[r.slab-1] {
+		a.dead[r.slab-1] += int64(c.size)
+		if r.slab != c.cur {
+			a.stranded += int64(c.size)
+		}
+		return
+	}
+	c.free = append(c.free, uint64(r.slab)<<32|uint64(r.off))
+}
+
+// release 归还已经没有 item 的 slab，并从 free 中删除指向它的 item
+func (a *Arena) release(c *class, id uint32) {
+	free := c.free[:0]
+	for _, p := range c.free {
+		if uint32(p>>32) != id {
+			free = append(free, p)
+		}
+	}
+	c.free = free
+	a.slabs[id-1] = nil
+	a.stranded -= a.dead[id-1]
+	a.dead[id-1] = 0
+	a.spare = append(a.spare, id)
+	a.count--
+}
+
+// Bytes 返回 r 指向的空间，只在 Free 之前有效
+func (a *Arena) Bytes(r Ref) []byte {
+	return a.slabs[r.slab-1][r.off : r.off+r.n : r.off+r.n]
+}
+
+// Share 返回 r 指向的空间，调用方可以一直持有，但是不能修改。
+// 之后 r 所在的 slab 中被释放的 item 不再复用，直到 slab 被归还
+func (a *Arena) Share(r Ref) []byte {
+	a.shared[r.slab-1] = true
+	return a.Bytes(r)
+}
+
+// Reset 释放所有的 slab，之前的 Ref 全部失效，Share 返回的空间仍然有效
+func (a *Arena) Reset() {
+	a.slabs, a.live, a.spare = nil, nil, nil
+	a.shared, a.dead = nil, nil
+	a.count = 0
+	a.used = 0
+	a.stranded = 0
+	for i := range a.classes {
+		a.classes[i] = class{size: a.classes[i].size}
+	}
+}
+
+func (a *Arena) Stats() Stats {
+	return Stats{
+		Slabs:         a.count,
+		SlabBytes:     int64(a.count) * int64(a.slabSize),
+		UsedBytes:     a.used,
+		StrandedBytes: a.stranded,
+	}
+}
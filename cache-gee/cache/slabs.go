@@ -0,0 +1,26 @@
+package cache
+
+import "cache/slab"
+
+// EnableSlabStorage 之后写入的缓存值拷贝到按照 size class 预先分配的大块内存中，
+// 堆上不再有大量小的 []byte，GC 需要标记的对象随之减少。
+// 超过最大 size class 的值仍然保存在堆上。
+//
+// 命中时返回的 ByteView 直接引用 slab 中的空间，不会拷贝，见 BenchmarkGetSlabValues。
+// 被读取过的 slab 中淘汰的空间不再复用，slab 中的值全部被淘汰之后归还给堆，
+// 仍然持有的 ByteView 使 slab 不会被 GC 回收，所以不受之后的写入影响。
+//
+// cacheBytes 按照 size class 的大小计算缓存值，因为被读取过而不能复用的空间同样计算在内，
+// 超出时继续淘汰最旧的缓存；其余被淘汰的空间优先给同一个 size class 复用。
+// 空闲但是还没有归还的空间在 MemoryBudget 分配时计算在内。
+// 调用多次时 panic
+func (g *Group) EnableSlabStorage(opts slab.Options) {
+	if !g.mainCache.enableSlabs(opts) {
+		panic("EnableSlabStorage called more than once")
+	}
+}
+
+// SlabStats 返回 slab 的内存使用情况，没有开启时返回零值
+func (g *Group) SlabStats() slab.Stats {
+	return g.mainCache.slabStats()
+}
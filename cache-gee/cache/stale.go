@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	defaultStaleBytes = 4 << 20
+	defaultStaleGrace = 5 * time.Minute
+)
+
+// StaleOptions stale-on-error 的配置
+// 因为容量不足被淘汰或者已经过期的缓存值会再保留 Grace，
+// 从其他节点以及 Getter 加载都失败时返回这些旧值，ByteView.Stale 为 true
+type StaleOptions struct {
+	MaxBytes int64         // 保留的旧值占用的内存上限，默认 4MB
+	Grace    time.Duration // 淘汰或者过期之后保留的时间，默认 5 分钟
+}
+
+// SetStaleOnError 开启 stale-on-error，opts 为 nil 时关闭，重新设置时已经保留的旧值会被清空
+// Remove、InvalidateTag 删除的以及 BumpGeneration 之前写入的缓存值不会被返回
+func (g *Group) SetStaleOnError(opts *StaleOptions) {
+	if opts == nil {
+		g.mainCache.setStale(0, 0, false)
+		return
+	}
+	maxBytes, grace := opts.MaxBytes, opts.Grace
+	if maxBytes <= 0 {
+		maxBytes = defaultStaleBytes
+	}
+	if grace <= 0 {
+		grace = defaultStaleGrace
+	}
+	g.mainCache.setStale(maxBytes, grace, true)
+}
+
+// staleOnError 加载失败时返回保留的旧值，数据源明确返回 ErrNotFound 时不使用
+func (g *Group) staleOnError(key string, err error) (ByteView, bool) {
+	if errors.Is(err, ErrNotFound) {
+		return ByteView{}, false
+	}
+	v, ok := g.mainCache.getStale(key, g.Generation())
+	if ok {
+		log.Printf("[GeeCache] serving stale %s after error: %v", key, err)
+		g.Stats.StaleServed.Add(1)
+	}
+	return v, ok
+}
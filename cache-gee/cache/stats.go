@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// AtomicInt 可以并发读写的 int64
+type AtomicInt int64
+
+// Add atomically adds n to i.
+func (i *AtomicInt) Add(n int64) {
+	atomic.AddInt64((*int64)(i), n)
+}
+
+// Get atomically gets the value of i.
+func (i *AtomicInt) Get() int64 {
+	return atomic.LoadInt64((*int64)(i))
+}
+
+func (i *AtomicInt) String() string {
+	return strconv.FormatInt(i.Get(), 10)
+}
+
+// Stats group 的统计信息
+type Stats struct {
+	Gets           AtomicInt // 所有的 Get 请求，包括来自其他节点的
+	CacheHits      AtomicInt // 命中 mainCache
+	Loads          AtomicInt // Gets - CacheHits
+	LoadsDeduped   AtomicInt // 经过 singleflight 合并之后真正执行的 load
+	PeerLoads      AtomicInt // 从其他节点获取成功
+	PeerErrors     AtomicInt // 从其他节点获取失败
+	LocalLoads     AtomicInt // 通过 Getter 加载成功
+	LocalLoadErrs  AtomicInt // 通过 Getter 加载失败
+	ServerRequests AtomicInt // 来自其他节点的 Get 请求
+	Sets           AtomicInt
+	Removes        AtomicInt
+	LoadsQueued    AtomicInt // 超出 LoadLimits.MaxConcurrent 后排队的 Getter 调用
+	LoadsRejected  AtomicInt // 超出 LoadLimits 被拒绝的 Getter 调用
+	HedgesFired    AtomicInt // 发出的 hedged request
+	HedgeWins      AtomicInt // hedged request 先于 owner 返回
+
+	StoreWrites     AtomicInt // 成功写入 Setter/Deleter 的修改
+	StoreWriteErrs  AtomicInt // 写入 Setter/Deleter 失败，write-behind 模式下是重试之后仍然失败的修改
+	WritesQueued    AtomicInt // 进入 write-behind 队列的修改
+	WritesCoalesced AtomicInt // 在写入之前被同一个 key 之后的修改覆盖
+	WriteRetries    AtomicInt // write-behind 重试的次数
+
+	HotKeyMirrors AtomicInt // 从 owner 获取之后在本地保存一份的热点 key
+	StaleServed   AtomicInt // 加载失败时返回的旧值
+	Incrs         AtomicInt // 在本地执行的 Incr、Decr
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tcpServer 管理 listener 以及连接，RedisServer 和 MemcacheServer 共用
+type tcpServer struct {
+	mtx      sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	started  time.Time
+}
+
+func newTCPServer() *tcpServer {
+	return &tcpServer{conns: make(map[net.Conn]struct{})}
+}
+
+// serve 为 l 上的每一个连接启动一个协程执行 handle，直到 Close 被调用
+func (s *tcpServer) serve(l net.Listener, handle func(conn net.Conn)) error {
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		l.Close()
+		return errors.New("server closed")
+	}
+	s.listener = l
+	s.started = time.Now()
+	s.mtx.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mtx.Lock()
+			closed := s.closed
+			s.mtx.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		s.mtx.Lock()
+		s.conns[conn] = struct{}{}
+		s.mtx.Unlock()
+		go func() {
+			defer func() {
+				s.mtx.Lock()
+				delete(s.conns, conn)
+				s.mtx.Unlock()
+				conn.Close()
+			}()
+			handle(conn)
+		}()
+	}
+}
+
+// Close 关闭 listener 以及所有连接
+func (s *tcpServer) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// status 返回当前的连接数以及运行时间
+func (s *tcpServer) status() (conns int, uptime time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.conns), time.Since(s.started)
+}
+
+// lookupGroup 将 {groupName}:{key} 解析为 group 和 key，不包含 ':' 时使用 defaultGroup
+func lookupGroup(r *Registry, defaultGroup, name string) (*Group, string, error) {
+	groupName, key := defaultGroup, name
+	if idx := strings.IndexByte(name, ':'); idx >= 0 {
+		groupName, key = name[:idx], name[idx+1:]
+	}
+	if groupName == "" {
+		return nil, "", errors.New("key must be in the form group:key")
+	}
+	group := r.orDefault().GetGroup(groupName)
+	if group == nil {
+		return nil, "", fmt.Errorf("no such group: %s", groupName)
+	}
+	return group, key, nil
+}
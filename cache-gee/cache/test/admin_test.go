@@ -0,0 +1,111 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cache"
+)
+
+func adminDo(t *testing.T, method, url string, out interface{}) int {
+	req, _ := http.NewRequest(method, url, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if out != nil && res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.StatusCode
+}
+
+func TestAdmin(t *testing.T) {
+	g := cache.ReplaceGroup("admin", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value"), nil
+		}))
+	for i := 0; i < 5; i++ {
+		g.Get(fmt.Sprintf("key%d", i))
+	}
+
+	server := httptest.NewServer(cache.NewAdmin())
+	defer server.Close()
+	base := server.URL + "/_cache_admin/"
+
+	var groups []cache.GroupInfo
+	adminDo(t, http.MethodGet, base, &groups)
+	found := false
+	for _, info := range groups {
+		if info.Name == "admin" {
+			found = info.Items == 5 && info.Bytes == 5*(4+5)
+		}
+	}
+	if !found {
+		t.Fatalf("group admin not listed correctly: %+v", groups)
+	}
+
+	var page cache.KeyPage
+	adminDo(t, http.MethodGet, base+"admin/keys?offset=1&limit=2", &page)
+	if page.Total != 5 || len(page.Keys) != 2 || page.Keys[0] != "key3" || page.Keys[1] != "key2" {
+		t.Fatalf("unexpected key page: %+v", page)
+	}
+
+	var info cache.KeyInfo
+	if code := adminDo(t, http.MethodGet, base+"admin/keys/key1", &info); code != http.StatusOK || info.Size != 5 {
+		t.Fatalf("unexpected key info: %d %+v", code, info)
+	}
+	if code := adminDo(t, http.MethodDelete, base+"admin/keys/key1", nil); code != http.StatusNoContent {
+		t.Fatalf("delete key: %d", code)
+	}
+	if code := adminDo(t, http.MethodGet, base+"admin/keys/key1", nil); code != http.StatusNotFound {
+		t.Fatalf("deleted key still exists: %d", code)
+	}
+
+	var group cache.GroupInfo
+	adminDo(t, http.MethodPut, base+"admin?cacheBytes=18", &group)
+	if group.CacheBytes != 18 || group.Items != 2 {
+		t.Fatalf("resize failed: %+v", group)
+	}
+	adminDo(t, http.MethodDelete, base+"admin", &group)
+	if group.Items != 0 || group.Bytes != 0 {
+		t.Fatalf("purge failed: %+v", group)
+	}
+	if code := adminDo(t, http.MethodGet, base+"missing", nil); code != http.StatusNotFound {
+		t.Fatalf("expect 404 for missing group, got %d", code)
+	}
+}
+
+// 以 /hotkeys 结尾的 key 仍然可以通过 keys 接口查看和删除
+func TestAdminHotKeysSuffix(t *testing.T) {
+	g := cache.ReplaceGroup("admin-suffix", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value"), nil
+		}))
+	g.SetHotKeys(&cache.HotKeyOptions{K: 4})
+	g.Get("x/hotkeys")
+
+	server := httptest.NewServer(cache.NewAdmin())
+	defer server.Close()
+	base := server.URL + "/_cache_admin/admin-suffix"
+
+	var info cache.KeyInfo
+	if code := adminDo(t, http.MethodGet, base+"/keys/x/hotkeys", &info); code != http.StatusOK || info.Key != "x/hotkeys" {
+		t.Fatalf("get key: %d %+v", code, info)
+	}
+	var report cache.HotKeyReport
+	if code := adminDo(t, http.MethodGet, base+"/hotkeys", &report); code != http.StatusOK || len(report.Keys) != 1 {
+		t.Fatalf("hotkeys: %d %+v", code, report)
+	}
+	if code := adminDo(t, http.MethodDelete, base+"/keys/x/hotkeys", nil); code != http.StatusNoContent {
+		t.Fatalf("delete key: %d", code)
+	}
+	if code := adminDo(t, http.MethodGet, base+"/keys/x/hotkeys", nil); code != http.StatusNotFound {
+		t.Fatalf("deleted key still exists: %d", code)
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// newBudgetGroup 创建 group 并写入 n 个 10 bytes 的缓存项
+func newBudgetGroup(t *testing.T, r *cache.Registry, name string, cacheBytes int64, n int) *cache.Group {
+	g, err := r.NewGroup(name, cacheBytes, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value"), nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		g.Get(fmt.Sprintf("k%04d", i))
+	}
+	return g
+}
+
+func TestMemoryBudgetRebalance(t *testing.T) {
+	r := cache.NewRegistry()
+	// 两个 group 都因为容量不足淘汰过缓存
+	hot := newBudgetGroup(t, r, "hot", 100, 20)
+	cold := newBudgetGroup(t, r, "cold", 100, 20)
+	for i := 0; i < 50; i++ {
+		hot.Get("k0019")
+	}
+	hot.SetCacheBounds(0, 700)
+	cold.SetCacheBounds(200, 0)
+
+	r.SetMemoryBudget(&cache.MemoryBudget{TotalBytes: 1000, Interval: time.Hour})
+	defer r.SetMemoryBudget(nil)
+	if hot.CacheBytes() != 700 || cold.CacheBytes() != 300 {
+		t.Fatalf("unexpected allocation: hot=%d cold=%d", hot.CacheBytes(), cold.CacheBytes())
+	}
+
+	// 没有容量压力的 group 最多分到已使用内存的两倍
+	idle := newBudgetGroup(t, r, "idle", 1000, 3)
+	r.Rebalance()
+	if idle.CacheBytes() != 60 {
+		t.Fatalf("idle group: expect 60, got %d", idle.CacheBytes())
+	}
+	if sum := hot.CacheBytes() + cold.CacheBytes() + idle.CacheBytes(); sum > 1000 {
+		t.Fatalf("allocated %d bytes over budget", sum)
+	}
+}
+
+func TestMemoryBudgetHeapSoftLimit(t *testing.T) {
+	r := cache.NewRegistry()
+	a := newBudgetGroup(t, r, "a", 1000, 50)
+	b := newBudgetGroup(t, r, "b", 1000, 50)
+	a.SetCacheBounds(100, 0)
+
+	// heap 一定超过 1 byte，收缩到下限
+	r.SetMemoryBudget(&cache.MemoryBudget{TotalBytes: 1 << 20, HeapSoftLimit: 1, Interval: time.Hour})
+	defer r.SetMemoryBudget(nil)
+	if a.CacheBytes() != 100 || b.CacheBytes() != 1 {
+		t.Fatalf("expect caches shrunk to bounds: a=%d b=%d", a.CacheBytes(), b.CacheBytes())
+	}
+	if v, err := a.Get("k0049"); err != nil || v.String() != "value" {
+		t.Fatalf("group still usable after shrink: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+func TestCompareAndSet(t *testing.T) {
+	g := cache.ReplaceGroup("cas", 0, cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("db-" + key), nil
+	}))
+
+	v, version, err := g.GetVersioned("k")
+	if err != nil || v.String() != "db-k" || version == 0 {
+		t.Fatalf("get versioned: %v %q %d", err, v, version)
+	}
+	next, err := g.CompareAndSet("k", version, []byte("v1"), time.Time{})
+	if err != nil || next == version {
+		t.Fatalf("compare and set: %v %d", err, next)
+	}
+
+	// 旧的版本返回冲突，并包含当前的版本
+	_, err = g.CompareAndSet("k", version, []byte("v2"), time.Time{})
+	var conflict *cache.VersionConflictError
+	if !errors.Is(err, cache.ErrVersionConflict) || !errors.As(err, &conflict) || conflict.Actual != next {
+		t.Fatalf("expect conflict with actual %d, got %v", next, err)
+	}
+	if v, got, _ := g.GetVersioned("k"); v.String() != "v1" || got != next {
+		t.Fatalf("value changed by conflicting write: %q %d", v, got)
+	}
+
+	// Set 同样会修改版本
+	g.Set("k", []byte("v3"), time.Time{})
+	if _, err = g.CompareAndSet("k", next, []byte("v4"), time.Time{}); !errors.Is(err, cache.ErrVersionConflict) {
+		t.Fatalf("expect conflict after Set, got %v", err)
+	}
+
+	// expected 为 0 时只在没有缓存时写入
+	if _, err = g.CompareAndSet("new", 0, []byte("n"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.CompareAndSet("new", 0, []byte("n"), time.Time{}); !errors.Is(err, cache.ErrVersionConflict) {
+		t.Fatalf("expect conflict for existing key, got %v", err)
+	}
+}
+
+func TestCompareAndSetOnPeer(t *testing.T) {
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "cas-peer", cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("0"), nil
+	}))
+	key := keyOwnedBy(nodes, nodes[2])
+
+	// 不同节点上并发的 read-modify-write，冲突时重试，最终没有丢失更新
+	const n = 10
+	var wg sync.WaitGroup
+	for _, g := range groups[:2] {
+		wg.Add(1)
+		go func(g *cache.Group) {
+			defer wg.Done()
+			for i := 0; i < n; {
+				v, version, err := g.GetVersioned(key)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				_, err = g.CompareAndSet(key, version, []byte(v.String()+"+"), time.Time{})
+				if errors.Is(err, cache.ErrVersionConflict) {
+					continue
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				i++
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	v, _, err := groups[0].GetVersioned(key)
+	if err != nil || len(v.String()) != 1+2*n {
+		t.Fatalf("lost updates: %v %q", err, v)
+	}
+}
+
+func TestCompareAndSetStreamed(t *testing.T) {
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{StreamThreshold: 16})
+	groups := newClusterGroup(t, nodes, "cas-stream", cache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, cache.ErrNotFound
+	}))
+	key := keyOwnedBy(nodes, nodes[2])
+
+	// 超过 StreamThreshold 的值以流的方式返回，版本放在 header 中
+	big := strings.Repeat("x", 64)
+	if err := groups[2].Set(key, []byte(big), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	v, version, err := groups[0].GetVersioned(key)
+	if err != nil || v.String() != big || version == 0 {
+		t.Fatalf("get streamed value: %v %d %d", err, v.Len(), version)
+	}
+	if _, err := groups[1].CompareAndSet(key, version, []byte("small"), time.Time{}); err != nil {
+		t.Fatalf("compare and set with streamed version: %v", err)
+	}
+}
+
+// gateStore 写入 block 时等待 release，release 的错误作为写入的结果
+type gateStore struct {
+	mtx     sync.Mutex
+	data    map[string]string
+	block   string
+	entered chan struct{}
+	release chan error
+}
+
+func newGateStore(block string) *gateStore {
+	return &gateStore{data: map[string]string{}, block: block, entered: make(chan struct{}), release: make(chan error)}
+}
+
+func (s *gateStore) Get(key string) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if v, ok := s.data[key]; ok {
+		return []byte(v), nil
+	}
+	return nil, cache.ErrNotFound
+}
+
+func (s *gateStore) Set(key string, value []byte) error {
+	if string(value) == s.block {
+		close(s.entered)
+		if err := <-s.release; err != nil {
+			return err
+		}
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.data[key] = string(value)
+	return nil
+}
+
+func TestCompareAndSetOrder(t *testing.T) {
+	store := newGateStore("a")
+	g := cache.ReplaceGroup("cas-order", 0, store)
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.CompareAndSet("k", 0, []byte("a"), time.Time{})
+		first <- err
+	}()
+	<-store.entered
+
+	// 使用第一次写入的版本，需要等第一次写入数据源之后才会执行
+	_, version, err := g.GetVersioned("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := make(chan error, 1)
+	go func() {
+		_, err := g.CompareAndSet("k", version, []byte("b"), time.Time{})
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	store.release <- nil
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := store.Get("k"); string(v) != "b" {
+		t.Fatalf("store written out of order: %q", v)
+	}
+	if v, _ := g.Get("k"); v.String() != "b" {
+		t.Fatalf("unexpected cache value %q", v)
+	}
+
+	// 写入数据源失败时不删除期间 Set 的值
+	store = newGateStore("x")
+	g = cache.ReplaceGroup("cas-order", 0, store)
+	go func() {
+		_, err := g.CompareAndSet("j", 0, []byte("x"), time.Time{})
+		first <- err
+	}()
+	<-store.entered
+	if err := g.Set("j", []byte("c"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	store.release <- errors.New("store unavailable")
+	if err := <-first; err == nil {
+		t.Fatal("expect store error")
+	}
+	if v, err := g.Get("j"); err != nil || v.String() != "c" {
+		t.Fatalf("value written by Set removed: %v %q", err, v)
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// node 一个使用独立 Registry 的缓存节点
+type node struct {
+	registry *cache.Registry
+	pool     *cache.HttpPool
+	server   *httptest.Server
+}
+
+// newCluster 启动 n 个互相连接的节点
+func newCluster(t *testing.T, n int, opts cache.HttpPoolOptions) []*node {
+	nodes := make([]*node, n)
+	addrs := make([]string, n)
+	for i := range nodes {
+		nd := &node{registry: cache.NewRegistry()}
+		nd.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nd.pool.ServeHTTP(w, r)
+		}))
+		o := opts
+		o.Registry = nd.registry
+		nd.pool = cache.NewHttpPoolOpts(nd.server.URL, &o)
+		nodes[i], addrs[i] = nd, nd.server.URL
+	}
+	for _, nd := range nodes {
+		nd.pool.Set(addrs...)
+		t.Cleanup(nd.server.Close)
+	}
+	return nodes
+}
+
+// newClusterGroup 在每个节点上创建同名的 group
+func newClusterGroup(t *testing.T, nodes []*node, name string, getter cache.Getter) []*cache.Group {
+	groups := make([]*cache.Group, len(nodes))
+	for i, nd := range nodes {
+		g, err := nd.registry.NewGroup(name, 0, getter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.RegisterPeers(nd.pool)
+		groups[i] = g
+	}
+	return groups
+}
+
+func TestBumpGeneration(t *testing.T) {
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{})
+	version := "v1"
+	groups := newClusterGroup(t, nodes, "gen", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(version), nil
+		}))
+
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, g := range groups {
+		for _, k := range keys {
+			if v, err := g.Get(k); err != nil || v.String() != "v1" {
+				t.Fatalf("get %s: %v %q", k, err, v)
+			}
+		}
+	}
+
+	version = "v2"
+	if err := groups[0].BumpGeneration(); err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range groups {
+		if g.Generation() != 1 {
+			t.Fatalf("generation not propagated to %s", g.Name())
+		}
+		for _, k := range keys {
+			if v, err := g.Get(k); err != nil || v.String() != "v2" {
+				t.Fatalf("get %s after bump: %v %q", k, err, v)
+			}
+		}
+	}
+}
+
+func TestRemoveExisted(t *testing.T) {
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "remove", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, cache.ErrNotFound
+		}))
+	key := keyOwnedBy(nodes, nodes[1])
+	groups[0].Set(key, []byte("v"), time.Time{})
+
+	// owner 返回 key 是否存在于缓存中
+	for _, expect := range []bool{true, false} {
+		existed, err := groups[0].Remove(key)
+		if err != nil || existed != expect {
+			t.Fatalf("remove: expect existed=%v, got %v %v", expect, existed, err)
+		}
+	}
+}
+
+func TestTouch(t *testing.T) {
+	store := newMemStore()
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "touch", store)
+	key := keyOwnedBy(nodes, nodes[1])
+	if err := groups[0].Set(key, []byte("v"), time.Time{}, "t"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只修改 owner 上的过期时间，不会写入数据源
+	store.fails = 100
+	if found, err := groups[0].Touch(key, time.Now().Add(time.Hour)); err != nil || !found {
+		t.Fatalf("touch: %v %v", found, err)
+	}
+	if v, _ := groups[1].Get(key); v.String() != "v" || v.Expire().IsZero() {
+		t.Fatalf("expect expire updated: %q %v", v, v.Expire())
+	}
+	// tag 保持不变
+	groups[0].InvalidateTag("t")
+	if found, _ := groups[0].Touch(key, time.Time{}); found {
+		t.Fatal("expect key invalidated by tag after touch")
+	}
+	// 没有缓存的 key 不会被加载
+	if found, _ := groups[0].Touch("Tom", time.Time{}); found {
+		t.Fatal("touch should not load uncached keys")
+	}
+}
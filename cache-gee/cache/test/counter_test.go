@@ -0,0 +1,98 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+func TestIncr(t *testing.T) {
+	loads := 0
+	g := cache.ReplaceGroup("counter", 0, cache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("db-" + key), nil
+	}))
+
+	// 没有缓存的 key 从 0 开始，不调用 Getter
+	for _, c := range []struct {
+		delta  int64
+		expect int64
+	}{{1, 1}, {5, 6}, {-10, -4}} {
+		if n, err := g.Incr("views", c.delta, 0); err != nil || n != c.expect {
+			t.Fatalf("incr %d: %v %d, expect %d", c.delta, err, n, c.expect)
+		}
+	}
+	if n, _ := g.Decr("views", 6, 0); n != -10 {
+		t.Fatalf("decr: expect -10, got %d", n)
+	}
+	if v, err := g.Get("views"); err != nil || v.String() != "-10" || loads != 0 {
+		t.Fatalf("get counter: %v %q loads=%d", err, v, loads)
+	}
+
+	// 不是整数以及溢出时不修改原来的值
+	g.Get("name")
+	if _, err := g.Incr("name", 1, 0); !errors.Is(err, cache.ErrNotInteger) {
+		t.Fatalf("expect ErrNotInteger, got %v", err)
+	}
+	g.Incr("max", math.MaxInt64, 0)
+	if _, err := g.Incr("max", 1, 0); !errors.Is(err, cache.ErrNotInteger) {
+		t.Fatalf("expect overflow error, got %v", err)
+	}
+	if n, _ := g.Incr("max", 0, 0); n != math.MaxInt64 {
+		t.Fatalf("counter changed by overflow: %d", n)
+	}
+
+	// ttl 只在创建时生效，过期之后重新从 0 开始
+	g.Incr("rate", 1, 50*time.Millisecond)
+	g.Incr("rate", 1, time.Hour)
+	if v, _ := g.Get("rate"); v.Expire().After(time.Now().Add(time.Minute)) {
+		t.Fatalf("ttl of existing counter changed: %v", v.Expire())
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n, _ := g.Incr("rate", 1, 0); n != 1 {
+		t.Fatalf("expect expired counter to restart, got %d", n)
+	}
+}
+
+func TestIncrOnPeer(t *testing.T) {
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "counter-peer", cache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, cache.ErrNotFound
+	}))
+	key := keyOwnedBy(nodes, nodes[2])
+
+	const n = 20
+	var wg sync.WaitGroup
+	for _, g := range groups {
+		wg.Add(1)
+		go func(g *cache.Group) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if _, err := g.Incr(key, 2, 0); err != nil {
+					t.Error(err)
+				}
+				if _, err := g.Decr(key, 1, 0); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// 计数器只保存在 owner 上
+	if v, err := groups[0].Get(key); err != nil || v.String() != "60" {
+		t.Fatalf("get counter from peer: %v %q", err, v)
+	}
+	if got := groups[2].Stats.Incrs.Get(); got != 2*3*n {
+		t.Fatalf("expect all increments on the owner, got %d", got)
+	}
+
+	groups[1].Set(key, []byte("text"), time.Time{})
+	if _, err := groups[0].Incr(key, 1, 0); !errors.Is(err, cache.ErrNotInteger) {
+		t.Fatalf("expect ErrNotInteger from peer, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cache"
+	"cache/disk"
+)
+
+func openStore(t *testing.T, dir string, opts disk.Options) *disk.Store {
+	t.Helper()
+	s, err := disk.Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func diskGet(t *testing.T, s *disk.Store, key string) (string, bool) {
+	t.Helper()
+	b, _, ok, err := s.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), ok
+}
+
+func TestDiskStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, disk.Options{})
+	for i := 0; i < 10; i++ {
+		if err := s.Put(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Put("key1", []byte("new"))
+	s.Delete("key2")
+	s.Close()
+
+	// 末尾写了一半的记录在重启时被截断
+	segs, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	f, _ := os.OpenFile(segs[len(segs)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write([]byte{1, 2, 3, 4, 5})
+	f.Close()
+
+	s = openStore(t, dir, disk.Options{})
+	s.Close()
+	// header 中的长度超出文件的记录同样被截断，不会按照它分配内存
+	f, _ = os.OpenFile(segs[len(segs)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write([]byte{0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 'k'})
+	f.Close()
+
+	s = openStore(t, dir, disk.Options{})
+	if v, ok := diskGet(t, s, "key1"); !ok || v != "new" {
+		t.Fatalf("key1: %q %v", v, ok)
+	}
+	if _, ok := diskGet(t, s, "key2"); ok {
+		t.Fatal("deleted key should stay deleted after restart")
+	}
+	if v, ok := diskGet(t, s, "key9"); !ok || v != "value9" {
+		t.Fatalf("key9: %q %v", v, ok)
+	}
+	if st := s.Stats(); st.Keys != 9 {
+		t.Fatalf("expect 9 keys, got %+v", st)
+	}
+	if err := s.Put("key10", []byte("value10")); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := diskGet(t, s, "key10"); !ok || v != "value10" {
+		t.Fatalf("write after truncation: %q %v", v, ok)
+	}
+}
+
+func TestDiskStoreCompactAndEvict(t *testing.T) {
+	dir := t.TempDir()
+	value := []byte(strings.Repeat("x", 100))
+	s := openStore(t, dir, disk.Options{MaxBytes: 64 << 10, SegmentBytes: 4 << 10})
+
+	// 反复覆盖同一批 key，旧的 segment 几乎都是无效数据，会被压缩
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 20; i++ {
+			s.Put(fmt.Sprintf("key%d", i), value)
+		}
+	}
+	st := s.Stats()
+	if st.Keys != 20 || st.Compactions == 0 || st.Evictions != 0 {
+		t.Fatalf("expect compaction without eviction: %+v", st)
+	}
+	if st.FileBytes > 2*st.LiveBytes+4<<10 {
+		t.Fatalf("dead records not reclaimed: %+v", st)
+	}
+
+	// 超出 MaxBytes 之后丢弃最旧的 key
+	var evicted []string
+	s.OnEvict = func(key string) { evicted = append(evicted, key) }
+	for i := 0; i < 1000; i++ {
+		s.Put(fmt.Sprintf("new%d", i), value)
+	}
+	st = s.Stats()
+	if st.FileBytes > 64<<10 || len(evicted) == 0 || int64(len(evicted)) != st.Evictions {
+		t.Fatalf("expect eviction within budget: %+v, evicted %d", st, len(evicted))
+	}
+	if _, ok := diskGet(t, s, "key0"); ok {
+		t.Fatal("oldest keys should be evicted first")
+	}
+	if _, ok := diskGet(t, s, "new999"); !ok {
+		t.Fatal("newest key should be kept")
+	}
+
+	s.Close()
+	s = openStore(t, dir, disk.Options{MaxBytes: 64 << 10, SegmentBytes: 4 << 10})
+	if got := s.Stats(); got.Keys != st.Keys {
+		t.Fatalf("expect %d keys after restart, got %d", st.Keys, got.Keys)
+	}
+}
+
+// 删除 segment 文件失败时 Clear 返回错误，之后仍然可以写入
+func TestDiskStoreClearError(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, disk.Options{})
+	if err := s.Put("a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	// 用非空的目录替换 segment 文件，os.Remove 会失败
+	name := filepath.Join(dir, "00000001.seg")
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(name, "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Clear(); err == nil {
+		t.Fatal("expect clear to report the failed remove")
+	}
+	if err := s.Put("b", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := diskGet(t, s, "b"); !ok || v != "2" {
+		t.Fatalf("expect b=2, got %q %v", v, ok)
+	}
+	if _, ok := diskGet(t, s, "a"); ok {
+		t.Fatal("a should be cleared")
+	}
+}
+
+func TestDiskTier(t *testing.T) {
+	dir := t.TempDir()
+	loads := 0
+	getter := cache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("v-" + key), nil
+	})
+	// 每个值 len(key)+len(value)=6+8 bytes，内存中只能放下两个
+	g := cache.ReplaceGroup("disk", 28, getter)
+	if err := g.EnableDiskTier(dir, disk.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"key-01", "key-02", "key-03", "key-04", "key-05"}
+	for _, key := range keys {
+		g.Get(key)
+	}
+	if st := g.DiskStats(); st.Keys != 3 || st.Puts != 3 {
+		t.Fatalf("expect 3 keys demoted, got %+v", st)
+	}
+
+	// 淘汰的 key 从磁盘读取，不再调用 Getter
+	for _, key := range keys {
+		if v, err := g.Get(key); err != nil || v.String() != "v-"+key {
+			t.Fatalf("get %s: %v %q", key, err, v)
+		}
+	}
+	if loads != 5 {
+		t.Fatalf("expect disk hits instead of loads, loads=%d", loads)
+	}
+	if st := g.DiskStats(); st.Hits != 5 || st.Keys != 3 {
+		t.Fatalf("unexpected disk stats: %+v", st)
+	}
+
+	// 内存中写入新值之后，磁盘上的旧值不再有效
+	g.Set("key-01", []byte("set-key1"), time.Time{}, "t")
+	for _, key := range keys[1:] {
+		g.Get(key)
+	}
+	if v, _ := g.Get("key-01"); v.String() != "set-key1" {
+		t.Fatalf("stale value from disk: %q", v)
+	}
+
+	// Remove 和 InvalidateTag 同时作用于磁盘
+	g.Remove("key-02")
+	g.Get("key-03")
+	g.Get("key-04")
+	if err := g.InvalidateTag("t"); err != nil {
+		t.Fatal(err)
+	}
+	before := loads
+	g.Get("key-01")
+	g.Get("key-02")
+	if loads != before+2 {
+		t.Fatalf("removed keys should be reloaded, loads=%d", loads-before)
+	}
+}
+
+func TestDiskTierConcurrent(t *testing.T) {
+	g := cache.ReplaceGroup("disk-concurrent", 256, cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("db-" + key), nil
+	}))
+	if err := g.EnableDiskTier(t.TempDir(), disk.Options{SegmentBytes: 4 << 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个 goroutine 只修改自己的 key，淘汰、写入磁盘以及压缩与其他 goroutine 并发进行
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 300; i++ {
+				key := fmt.Sprintf("w%d-%d", w, i%40)
+				value := fmt.Sprintf("set-%d", i)
+				if err := g.Set(key, []byte(value), time.Time{}); err != nil {
+					t.Error(err)
+					return
+				}
+				if v, _ := g.Get(key); v.String() != value {
+					t.Errorf("get %s: expect %q, got %q", key, value, v)
+					return
+				}
+				if i%3 == 0 {
+					g.Remove(key)
+					if v, _ := g.Get(key); v.String() != "db-"+key {
+						t.Errorf("get %s after Remove: %q", key, v)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if st := g.DiskStats(); st.Puts == 0 || st.Compactions == 0 {
+		t.Fatalf("expect values demoted and compacted: %+v", st)
+	}
+}
+
+func TestDiskTierRestart(t *testing.T) {
+	dir := t.TempDir()
+	loads := 0
+	getter := cache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("v-" + key), nil
+	})
+	g, _ := cache.NewRegistry().NewGroup("disk-restart", 28, getter)
+	if err := g.EnableDiskTier(dir, disk.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	g.Set("key-01", []byte("set-key1"), time.Now().Add(time.Hour), "t1")
+	g.Set("key-02", []byte("set-key2"), time.Time{}, "t2")
+	g.Set("key-03", []byte("set-key3"), time.Time{})
+	g.Set("key-04", []byte("set-key4"), time.Time{})
+	if g.DiskStats().Keys != 2 {
+		t.Fatalf("expect 2 keys on disk: %+v", g.DiskStats())
+	}
+
+	// 模拟重启：新的 Registry 打开同一个目录，内存中的 key 丢失，磁盘上的仍然可以使用
+	r := cache.NewRegistry()
+	g2, _ := r.NewGroup("disk-restart", 28, getter)
+	if err := g2.EnableDiskTier(dir, disk.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := g2.Get("key-01")
+	if err != nil || v.String() != "set-key1" || v.Expire().IsZero() {
+		t.Fatalf("get after restart: %v %q %v", err, v, v.Expire())
+	}
+	// 重启之后 tag 索引仍然包括磁盘上的 key
+	g2.InvalidateTag("t2")
+	if v, _ := g2.Get("key-02"); v.String() != "v-key-02" || loads != 1 {
+		t.Fatalf("expect key-02 reloaded after InvalidateTag: %q loads=%d", v, loads)
+	}
+
+	// BumpGeneration 之后重启，磁盘上旧的值不再返回
+	g2.Set("key-05", []byte("old-key5"), time.Time{})
+	g2.Set("key-06", []byte("old-key6"), time.Time{})
+	g2.Set("key-07", []byte("old-key7"), time.Time{})
+	g2.BumpGeneration()
+	g3, _ := cache.NewRegistry().NewGroup("disk-restart", 28, getter)
+	if err := g3.EnableDiskTier(dir, disk.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if g3.Generation() != g2.Generation() {
+		t.Fatalf("expect generation %d restored, got %d", g2.Generation(), g3.Generation())
+	}
+	if v, _ := g3.Get("key-05"); v.String() != "v-key-05" {
+		t.Fatalf("invalidated value returned after restart: %q", v)
+	}
+
+	r.DeleteGroup("disk-restart")
+	if st := openStore(t, dir, disk.Options{}).Stats(); st.Keys != 0 {
+		t.Fatalf("expect DeleteGroup to clear the disk tier: %+v", st)
+	}
+}
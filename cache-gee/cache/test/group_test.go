@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"cache"
+	pb "cache/cachepb"
 )
 
 var db = map[string]string{
@@ -18,7 +23,7 @@ var db = map[string]string{
 func TestGroupGet(t *testing.T) {
 	loadCounts := make(map[string]int, len(db))
 
-	gee := cache.NewGroup("scores", 2<<10, cache.GetterFunc(
+	gee := cache.ReplaceGroup("scores", 2<<10, cache.GetterFunc(
 		func(key string) ([]byte, error) {
 			log.Println("[SlowDB] search key", key)
 			if v, ok := db[key]; ok {
@@ -47,7 +52,7 @@ func TestGroupGet(t *testing.T) {
 
 func TestHttp(t *testing.T) {
 
-	cache.NewGroup("scores", 2<<10, cache.GetterFunc(
+	cache.ReplaceGroup("scores", 2<<10, cache.GetterFunc(
 		func(key string) ([]byte, error) {
 			log.Println("[SlowDB] search key", key)
 			if v, ok := db[key]; ok {
@@ -56,8 +61,131 @@ func TestHttp(t *testing.T) {
 			return nil, fmt.Errorf("%s not exist", key)
 		}))
 
-	addr := "localhost:9999"
-	peers := cache.NewHttpPool(addr)
-	log.Println("cache server is running at", addr)
-	log.Fatal(http.ListenAndServe(addr, peers))
+	server := httptest.NewServer(cache.NewHttpPool("server"))
+	defer server.Close()
+	log.Println("cache server is running at", server.URL)
+
+	peers := cache.NewHttpPool("client")
+	peers.Set(server.URL)
+	peer, ok := peers.PickPeer("Tom")
+	if !ok {
+		t.Fatal("pick peer failed")
+	}
+	res := &pb.Response{}
+	if err := peer.Get(&pb.Request{Group: "scores", Key: "Tom"}, res); err != nil || string(res.Value) != db["Tom"] {
+		t.Fatalf("get Tom from peer failed: %v", err)
+	}
+}
+
+// countingTransport 记录经过的请求数量
+type countingTransport struct {
+	n int32
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHttpPoolOptions(t *testing.T) {
+	cache.ReplaceGroup("opts", 2<<10, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "slow" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			return []byte("v-" + key), nil
+		}))
+
+	// 和其他 handler 一起挂载到自定义的 mux 上
+	transport := &countingTransport{}
+	opts := &cache.HttpPoolOptions{
+		BasePath:  "/peers",
+		Replicas:  3,
+		Transport: transport,
+		Timeout:   50 * time.Millisecond,
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	pool := cache.NewHttpPoolOpts(server.URL, opts)
+	mux.Handle("/peers/", pool)
+	mux.Handle("/", pool) // 其他路径返回 404，而不是 panic
+
+	client := cache.NewHttpPoolOpts("client", opts)
+	client.Set(server.URL)
+	if v, err := peerGet(t, client, "opts", "k"); err != nil || v != "v-k" {
+		t.Fatalf("get under custom base path: %v %q", err, v)
+	}
+	if _, err := peerGet(t, client, "opts", "slow"); err == nil {
+		t.Fatal("expect timeout")
+	}
+	if n := atomic.LoadInt32(&transport.n); n != 2 {
+		t.Fatalf("expect 2 requests through custom transport, got %d", n)
+	}
+
+	res, err := http.Get(server.URL + "/distributed_cache/opts/k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expect 404 for unexpected path, got %d", res.StatusCode)
+	}
+}
+
+func TestHttpPoolHashFn(t *testing.T) {
+	pool := cache.NewHttpPoolOpts("http://a", &cache.HttpPoolOptions{
+		// 虚拟节点 b 在环上位于 a 之前，所有 key 都落在 b 上
+		HashFn: func(data []byte) uint32 {
+			switch {
+			case strings.HasSuffix(string(data), "http://a"):
+				return 200
+			case strings.HasSuffix(string(data), "http://b"):
+				return 100
+			}
+			return 50
+		},
+	})
+	pool.Set("http://a", "http://b")
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		peer, ok := pool.PickPeer(key)
+		if !ok || !strings.HasPrefix(fmt.Sprint(peer), "http://b/") {
+			t.Fatalf("%s: expect http://b, got %v", key, peer)
+		}
+	}
+}
+
+// getOnlyPeers 只实现了 PeerPicker 和 PeerGetter 必须的方法
+type getOnlyPeers struct{}
+
+func (p getOnlyPeers) PickPeer(key string) (cache.PeerGetter, bool) { return p, true }
+func (p getOnlyPeers) Get(in *pb.Request, out *pb.Response) error {
+	out.Value = []byte("peer-" + in.Key)
+	return nil
+}
+
+func TestGetOnlyPeers(t *testing.T) {
+	g := cache.ReplaceGroup("get-only", 2<<10, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	g.RegisterPeers(getOnlyPeers{})
+
+	if v, err := g.Get("k"); err != nil || v.String() != "peer-k" {
+		t.Fatalf("get: %v %q", err, v)
+	}
+	if err := g.Set("k", []byte("v"), time.Time{}); err == nil {
+		t.Fatal("expect set to fail on a peer without WritePeerGetter")
+	}
+	if _, err := g.Remove("k"); err == nil {
+		t.Fatal("expect remove to fail on a peer without WritePeerGetter")
+	}
+	// 没有实现 PeerLister 时只修改本地，通知其他节点失败
+	gen := g.Generation()
+	if err := g.BumpGeneration(); err == nil || g.Generation() != gen+1 {
+		t.Fatalf("bump generation: %v %d", err, g.Generation())
+	}
+	if err := g.InvalidateTag("t"); err == nil {
+		t.Fatal("expect invalidate tag to fail without PeerLister")
+	}
 }
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// hedgeCluster 启动 n 个节点，key 的 owner 加载很慢，返回 owner 以外的一个节点上的 group
+func hedgeCluster(t *testing.T, n int, key string) *cache.Group {
+	nodes := newCluster(t, n, cache.HttpPoolOptions{})
+	var requester *cache.Group
+	for _, nd := range nodes {
+		_, remote := nd.pool.PickPeer(key)
+		delay := time.Duration(0)
+		if !remote {
+			delay = time.Second
+		}
+		g, err := nd.registry.NewGroup("hedge", 0, cache.GetterFunc(
+			func(key string) ([]byte, error) {
+				time.Sleep(delay)
+				return []byte("v-" + key), nil
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.RegisterPeers(nd.pool)
+		if remote && requester == nil {
+			requester = g
+		}
+	}
+	return requester
+}
+
+func TestHedgeToReplica(t *testing.T) {
+	g := hedgeCluster(t, 3, "k")
+	g.SetHedging(&cache.HedgeOptions{MinDelay: 20 * time.Millisecond})
+
+	start := time.Now()
+	if v, err := g.Get("k"); err != nil || v.String() != "v-k" {
+		t.Fatalf("hedged get: %v %q", err, v)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("hedged get took %v", d)
+	}
+	if g.Stats.HedgesFired.Get() != 1 || g.Stats.HedgeWins.Get() != 1 {
+		t.Fatalf("unexpected hedge stats: fired=%d wins=%d",
+			g.Stats.HedgesFired.Get(), g.Stats.HedgeWins.Get())
+	}
+}
+
+func TestHedgeLocal(t *testing.T) {
+	// 只有两个节点时没有其他副本
+	g := hedgeCluster(t, 2, "k")
+	g.SetHedging(&cache.HedgeOptions{MinDelay: 20 * time.Millisecond})
+	start := time.Now()
+	if _, err := g.Get("k"); err != nil || g.Stats.HedgesFired.Get() != 0 {
+		t.Fatalf("hedge without AllowLocal: %v fired=%d", err, g.Stats.HedgesFired.Get())
+	}
+	if time.Since(start) < time.Second {
+		t.Fatal("expect the owner to answer")
+	}
+
+	// owner 已经缓存了 k，删除后再次加载仍然很慢
+	if _, err := g.Remove("k"); err != nil {
+		t.Fatal(err)
+	}
+	g.SetHedging(&cache.HedgeOptions{MinDelay: 20 * time.Millisecond, AllowLocal: true})
+	start = time.Now()
+	if v, err := g.Get("k"); err != nil || v.String() != "v-k" {
+		t.Fatalf("local hedge: %v %q", err, v)
+	}
+	if g.Stats.HedgeWins.Get() != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("local hedge did not win: wins=%d", g.Stats.HedgeWins.Get())
+	}
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cache"
+	"cache/hotkey"
+)
+
+func TestHotKeyTracker(t *testing.T) {
+	tr := hotkey.New(hotkey.Options{K: 5, Width: 256, HalfLife: time.Minute})
+	now := time.Now()
+
+	// 5 个热点 key 混在大量只访问一次的 key 中
+	for i := 0; i < 10000; i++ {
+		tr.Add(fmt.Sprintf("cold-%d", i), now)
+		if i%10 == 0 {
+			for j := 0; j < 5; j++ {
+				tr.Add(fmt.Sprintf("hot-%d", j), now)
+			}
+		}
+	}
+	top := tr.Top(now)
+	if len(top) != 5 {
+		t.Fatalf("expect 5 hot keys, got %+v", top)
+	}
+	for _, item := range top {
+		if item.Key[:4] != "hot-" || item.Count < 1000 {
+			t.Fatalf("unexpected hot keys: %+v", top)
+		}
+	}
+
+	// 每经过 HalfLife 计数减半
+	n, _ := tr.Count("hot-0")
+	tr.Top(now.Add(2*time.Minute + time.Second))
+	if m, ok := tr.Count("hot-0"); !ok || m != n>>2 {
+		t.Fatalf("expect count %d after two half lives, got %d", n>>2, m)
+	}
+	top = tr.Top(now.Add(time.Hour))
+	if len(top) != 0 {
+		t.Fatalf("expect all counts to decay to zero, got %+v", top)
+	}
+}
+
+func TestGroupHotKeys(t *testing.T) {
+	g := cache.ReplaceGroup("hotkeys", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	server := httptest.NewServer(cache.NewAdmin())
+	defer server.Close()
+	url := server.URL + "/_cache_admin/hotkeys/hotkeys"
+
+	if code := adminDo(t, http.MethodGet, url, nil); code != http.StatusNotFound {
+		t.Fatalf("expect 404 before SetHotKeys, got %d", code)
+	}
+
+	g.SetHotKeys(&cache.HotKeyOptions{K: 4, Threshold: 10})
+	for i := 0; i < 50; i++ {
+		g.Get("a")
+		if i%10 == 0 {
+			g.Get("b")
+		}
+	}
+	if keys := g.HotKeys(); len(keys) != 2 || keys[0].Key != "a" || keys[0].Count != 50 || keys[1].Count != 5 {
+		t.Fatalf("unexpected hot keys: %+v", keys)
+	}
+	if !g.IsHot("a") || g.IsHot("b") || g.IsHot("c") {
+		t.Fatal("only a should be hot")
+	}
+
+	var report cache.HotKeyReport
+	if code := adminDo(t, http.MethodGet, url, &report); code != http.StatusOK {
+		t.Fatalf("hotkeys: %d", code)
+	}
+	if len(report.Keys) != 2 || report.Keys[0].Key != "a" || !report.Keys[0].Hot || report.Keys[1].Hot {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	g.SetHotKeys(nil)
+	if g.HotKeys() != nil || g.IsHot("a") {
+		t.Fatal("expect tracking to be disabled")
+	}
+}
+
+func TestHotKeyMirror(t *testing.T) {
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "mirror", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+
+	// 找一个属于 nodes[1] 的 key
+	key := ""
+	for i := 0; key == ""; i++ {
+		k := fmt.Sprintf("key-%d", i)
+		if nodes[0].pool.Locate(k, 1).Owner == nodes[1].server.URL {
+			key = k
+		}
+	}
+
+	const ttl = 200 * time.Millisecond
+	groups[0].SetHotKeys(&cache.HotKeyOptions{Threshold: 5, Mirror: true, MirrorTTL: ttl})
+	for i := 0; i < 10; i++ {
+		if v, err := groups[0].Get(key); err != nil || v.String() != "v-"+key {
+			t.Fatalf("get: %v %q", err, v)
+		}
+	}
+	// 第 5 次 Get 时成为热点，之后从本地的副本返回
+	if n := groups[1].Stats.ServerRequests.Get(); n != 5 {
+		t.Fatalf("expect 5 requests to the owner, got %d", n)
+	}
+	if n := groups[0].Stats.HotKeyMirrors.Get(); n != 1 {
+		t.Fatalf("expect 1 mirror, got %d", n)
+	}
+
+	// 副本过期之后重新从 owner 获取
+	time.Sleep(ttl + 50*time.Millisecond)
+	groups[0].Get(key)
+	if n := groups[1].Stats.ServerRequests.Get(); n != 6 {
+		t.Fatalf("expect mirror to expire, owner requests = %d", n)
+	}
+}
+
+func BenchmarkGetHotKeys(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	for _, enabled := range []bool{false, true} {
+		g := cache.ReplaceGroup("bench-hotkeys", 0, cache.GetterFunc(
+			func(key string) ([]byte, error) {
+				return []byte(key), nil
+			}))
+		if enabled {
+			g.SetHotKeys(&cache.HotKeyOptions{})
+		}
+		for _, k := range keys {
+			g.Get(k)
+		}
+		b.Run(fmt.Sprintf("tracking=%v", enabled), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					g.Get(keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkHotKeyTracker 每次 Get 额外的开销
+func BenchmarkHotKeyTracker(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	tr := hotkey.New(hotkey.Options{})
+	now := time.Now()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Add(keys[i%len(keys)], now)
+	}
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+func TestLoadLimitsConcurrency(t *testing.T) {
+	started, unblock := make(chan struct{}, 10), make(chan struct{})
+	g := cache.ReplaceGroup("limits", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			started <- struct{}{}
+			<-unblock
+			return []byte(key), nil
+		}))
+	g.SetLoadLimits(cache.LoadLimits{MaxConcurrent: 1, MaxQueue: 1, MaxWait: 50 * time.Millisecond})
+
+	first := make(chan error)
+	go func() {
+		_, err := g.Get("a")
+		first <- err
+	}()
+	<-started
+
+	queued := make(chan error)
+	go func() {
+		_, err := g.Get("b")
+		queued <- err
+	}()
+	for g.Stats.LoadsQueued.Get() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 队列已满，直接拒绝
+	_, err := g.Get("c")
+	var overloaded *cache.OverloadedError
+	if !errors.Is(err, cache.ErrOverloaded) || !errors.As(err, &overloaded) || overloaded.Group != "limits" {
+		t.Fatalf("expect ErrOverloaded, got %v", err)
+	}
+	// 排队超时
+	if err = <-queued; !errors.Is(err, cache.ErrOverloaded) {
+		t.Fatalf("expect queued load to time out, got %v", err)
+	}
+
+	close(unblock)
+	if err = <-first; err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Get("d"); err != nil {
+		t.Fatalf("load after release failed: %v", err)
+	}
+	if g.Stats.LoadsQueued.Get() != 1 || g.Stats.LoadsRejected.Get() != 2 {
+		t.Fatalf("unexpected stats: queued=%d rejected=%d", g.Stats.LoadsQueued.Get(), g.Stats.LoadsRejected.Get())
+	}
+}
+
+func TestLoadLimitsRate(t *testing.T) {
+	g := cache.ReplaceGroup("rate", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	g.SetLoadLimits(cache.LoadLimits{Rate: 1, Burst: 2})
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := g.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.Get("c"); !errors.Is(err, cache.ErrOverloaded) {
+		t.Fatalf("expect rate limit, got %v", err)
+	}
+	// 命中缓存不受限制
+	if _, err := g.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLimitsRateRefund(t *testing.T) {
+	started, unblock := make(chan struct{}, 10), make(chan struct{})
+	g := cache.ReplaceGroup("rate-refund", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "slow" {
+				started <- struct{}{}
+				<-unblock
+			}
+			return []byte(key), nil
+		}))
+	// 令牌几乎不会恢复
+	g.SetLoadLimits(cache.LoadLimits{MaxConcurrent: 1, Rate: 0.001, Burst: 2})
+
+	done := make(chan error)
+	go func() {
+		_, err := g.Get("slow")
+		done <- err
+	}()
+	<-started
+
+	// 因为没有空闲的并发数被拒绝时归还令牌
+	if _, err := g.Get("a"); !errors.Is(err, cache.ErrOverloaded) {
+		t.Fatalf("expect load queue full, got %v", err)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("b"); err != nil {
+		t.Fatalf("token not returned after rejection: %v", err)
+	}
+}
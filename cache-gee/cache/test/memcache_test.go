@@ -0,0 +1,179 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+func startMemcache(t *testing.T) (*cache.MemcacheServer, net.Conn) {
+	cache.ReplaceGroup("mc", 2<<10, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s: %w", key, cache.ErrNotFound)
+		}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := cache.NewMemcacheServer()
+	server.DefaultGroup = "mc"
+	go server.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return server, conn
+}
+
+// expectLines 读取 n 行并与 expect 比较
+func expectLines(t *testing.T, r *bufio.Reader, expect ...string) {
+	for _, e := range expect {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("expect %q: %v", e, err)
+		}
+		if got := strings.TrimSuffix(line, "\r\n"); got != e {
+			t.Fatalf("expect %q, got %q", e, got)
+		}
+	}
+}
+
+func TestMemcacheProtocol(t *testing.T) {
+	server, conn := startMemcache(t)
+	defer server.Close()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(s string) {
+		if _, err := io.WriteString(conn, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("version\r\n")
+	expectLines(t, r, "VERSION 1.6.0-cache")
+
+	send("set k 0 0 5\r\nhello\r\n")
+	expectLines(t, r, "STORED")
+
+	// multi-key get，不存在的 key 直接跳过
+	send("get k Tom mc:nobody mc:Sam\r\n")
+	expectLines(t, r,
+		"VALUE k 0 5", "hello",
+		"VALUE Tom 0 3", "630",
+		"VALUE mc:Sam 0 3", "567",
+		"END")
+
+	send("gets k\r\n")
+	line, _ := r.ReadString('\n')
+	if !strings.HasPrefix(line, "VALUE k 0 5 ") {
+		t.Fatalf("unexpected gets line %q", line)
+	}
+	expectLines(t, r, "hello", "END")
+
+	// cas unique 使用 gets 返回的版本，写入之后版本变化
+	unique := strings.TrimSpace(strings.TrimPrefix(line, "VALUE k 0 5 "))
+	send("cas k 0 0 5 " + unique + "\r\nworld\r\n")
+	expectLines(t, r, "STORED")
+	send("cas k 0 0 5 " + unique + "\r\nagain\r\n")
+	expectLines(t, r, "EXISTS")
+	send("cas nobody 0 0 1 1\r\nx\r\n")
+	expectLines(t, r, "NOT_FOUND")
+	send("get k\r\n")
+	expectLines(t, r, "VALUE k 0 5", "world", "END")
+
+	// noreply 的命令没有任何回复，紧接着的 get 可以验证这一点
+	send("set nr 0 0 2 noreply\r\nok\r\nget nr\r\n")
+	expectLines(t, r, "VALUE nr 0 2", "ok", "END")
+
+	// flags 与缓存值一起保存，cas 写入新的 flags
+	send("set f 42 0 2\r\nhi\r\nget f\r\n")
+	expectLines(t, r, "STORED", "VALUE f 42 2", "hi", "END")
+	send("gets f\r\n")
+	line, _ = r.ReadString('\n')
+	if !strings.HasPrefix(line, "VALUE f 42 2 ") {
+		t.Fatalf("unexpected gets line %q", line)
+	}
+	expectLines(t, r, "hi", "END")
+	unique = strings.TrimSpace(strings.TrimPrefix(line, "VALUE f 42 2 "))
+	send("cas f 7 0 2 " + unique + "\r\nyo\r\nget f\r\n")
+	expectLines(t, r, "STORED", "VALUE f 7 2", "yo", "END")
+
+	send("touch k 100\r\n")
+	expectLines(t, r, "TOUCHED")
+	send("touch nobody 100\r\n")
+	expectLines(t, r, "NOT_FOUND")
+	// 数据源中存在但是没有缓存的 key 不会被加载
+	send("touch Jack 100\r\n")
+	expectLines(t, r, "NOT_FOUND")
+
+	send("delete k\r\nget k\r\n")
+	expectLines(t, r, "DELETED", "END")
+	send("delete k\r\n")
+	expectLines(t, r, "NOT_FOUND")
+	send("delete nr noreply\r\nget nr\r\n")
+	expectLines(t, r, "END")
+
+	send("set e 0 -1 1\r\nx\r\nget e\r\n")
+	expectLines(t, r, "STORED", "END")
+
+	send("bogus\r\n")
+	expectLines(t, r, "ERROR")
+
+	send("stats\r\n")
+	stats := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		if line == "END" {
+			break
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 || parts[0] != "STAT" {
+			t.Fatalf("bad stat line %q", line)
+		}
+		stats[parts[1]] = parts[2]
+	}
+	if stats["mc:cmd_set"] == "" || stats["mc:cmd_set"] == "0" || stats["curr_connections"] != "1" {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+
+	send("quit\r\n")
+	if _, err := r.ReadString('\n'); err != io.EOF {
+		t.Fatalf("expect connection closed after quit, got %v", err)
+	}
+}
+
+func TestMemcacheLimits(t *testing.T) {
+	server, conn := startMemcache(t)
+	defer server.Close()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// 过大的缓存值被丢弃，连接仍然可以使用
+	n := 1<<20 + 1
+	go io.WriteString(conn, fmt.Sprintf("set big 0 0 %d\r\n%s\r\nget Tom\r\n", n, strings.Repeat("x", n)))
+	expectLines(t, r, "SERVER_ERROR object too large for cache", "VALUE Tom 0 3", "630", "END")
+
+	// 没有换行符的命令行超过缓冲区之后关闭连接
+	go io.WriteString(conn, strings.Repeat("x", 64<<10))
+	expectLines(t, r, "CLIENT_ERROR line is too long")
+	// 服务端还有没有读取的数据，关闭时可能是 connection reset
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Fatal("expect connection to be closed")
+	}
+}
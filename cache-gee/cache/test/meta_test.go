@@ -0,0 +1,161 @@
+package test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cache"
+	"cache/slab"
+)
+
+// entryDB 每个值都带上版本和 content type，key 以 big 开头时返回超过 StreamThreshold 的值
+type entryDB struct {
+	expire time.Time
+	loads  int
+}
+
+func (db *entryDB) Get(key string) ([]byte, error) {
+	e, err := db.GetEntry(key)
+	return e.Value, err
+}
+
+func (db *entryDB) GetEntry(key string) (cache.Entry, error) {
+	db.loads++
+	value := []byte("db-" + key)
+	if strings.HasPrefix(key, "big") {
+		value = bigValue(key, 2<<20)
+	}
+	return cache.Entry{Value: value, Meta: cache.Meta{
+		Expire:          db.expire,
+		Version:         "v1-" + key,
+		ContentType:     "text/plain",
+		ContentEncoding: "identity",
+	}}, nil
+}
+
+func checkMeta(t *testing.T, what string, m cache.Meta, key string, expire time.Time) {
+	t.Helper()
+	if m.Version != "v1-"+key || m.ContentType != "text/plain" || m.ContentEncoding != "identity" {
+		t.Fatalf("%s %s: unexpected meta %+v", what, key, m)
+	}
+	if !m.Expire.Equal(expire) {
+		t.Fatalf("%s %s: expect expire %v, got %v", what, key, expire, m.Expire)
+	}
+}
+
+func TestPeerMeta(t *testing.T) {
+	expire := time.Now().Add(time.Hour).Round(0)
+	db := &entryDB{expire: expire}
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "meta", db)
+
+	// owner 和非 owner 上都能拿到相同的元信息，无论是否以流的方式传输
+	for _, key := range []string{"a", "b", "c", "big-a", "big-b"} {
+		for _, g := range groups {
+			v, err := g.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkMeta(t, "Get", v.Meta(), key, expire)
+			if !v.Expire().Equal(expire) {
+				t.Fatalf("Get %s: expire %v", key, v.Expire())
+			}
+
+			s, err := g.GetStream(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(s)
+			s.Close()
+			if err != nil || len(b) != v.Len() {
+				t.Fatalf("GetStream %s: %v %d bytes", key, err, len(b))
+			}
+			checkMeta(t, "GetStream", s.Meta, key, expire)
+		}
+	}
+}
+
+func TestSetEntryMeta(t *testing.T) {
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "set-meta", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("db-" + key), nil
+		}))
+
+	expire := time.Now().Add(300 * time.Millisecond).Round(0)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		err := groups[0].SetEntry(key, cache.Entry{
+			Value: []byte("set-" + key),
+			Meta:  cache.Meta{Expire: expire, Version: "v1-" + key, ContentType: "text/plain", ContentEncoding: "identity", Flags: 7},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, g := range groups {
+		for _, key := range []string{"a", "b", "c", "d"} {
+			v, err := g.Get(key)
+			if err != nil || v.String() != "set-"+key {
+				t.Fatalf("get %s: %v %q", key, err, v)
+			}
+			checkMeta(t, "Get", v.Meta(), key, expire)
+			if v.Meta().Flags != 7 {
+				t.Fatalf("get %s: expect flags 7, got %d", key, v.Meta().Flags)
+			}
+		}
+	}
+
+	// 写入时的过期时间在 owner 上生效
+	time.Sleep(time.Until(expire) + 50*time.Millisecond)
+	for _, g := range groups {
+		if v, err := g.Get("a"); err != nil || v.String() != "db-a" {
+			t.Fatalf("expect expired value to be reloaded: %v %q", err, v)
+		}
+	}
+}
+
+func TestMetaWriteHeaders(t *testing.T) {
+	expire := time.Now().Add(time.Minute)
+	m := cache.Meta{Expire: expire, Version: "v1", ContentType: "application/json", ContentEncoding: "gzip"}
+	w := httptest.NewRecorder()
+	m.WriteHeaders(w.Header())
+
+	h := w.Header()
+	if h.Get("Content-Type") != "application/json" || h.Get("Content-Encoding") != "gzip" || h.Get("ETag") != `"v1"` {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+	if cc := h.Get("Cache-Control"); cc != "max-age=59" && cc != "max-age=60" {
+		t.Fatalf("unexpected Cache-Control: %s", cc)
+	}
+	if h.Get("Expires") == "" {
+		t.Fatal("missing Expires")
+	}
+
+	// 没有元信息时不设置任何 header
+	w = httptest.NewRecorder()
+	cache.Meta{}.WriteHeaders(w.Header())
+	if len(w.Header()) != 0 {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+}
+
+func TestSlabMeta(t *testing.T) {
+	expire := time.Now().Add(time.Hour).Round(0)
+	db := &entryDB{expire: expire}
+	g := cache.ReplaceGroup("slab-meta", 1<<20, db)
+	g.EnableSlabStorage(slab.Options{})
+
+	for i := 0; i < 2; i++ {
+		v, err := g.Get("a")
+		if err != nil || v.String() != "db-a" {
+			t.Fatalf("get: %v %q", err, v)
+		}
+		checkMeta(t, "slab", v.Meta(), "a", expire)
+	}
+	if db.loads != 1 {
+		t.Fatalf("expect value served from slab, loads=%d", db.loads)
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cache"
+)
+
+func TestMetrics(t *testing.T) {
+	// 每个 value 占 len(key)+len(value)=10 bytes，只能放下两个
+	g := cache.ReplaceGroup("metrics", 20, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value"), nil
+		}))
+	for _, k := range []string{"key01", "key02", "key03", "key03"} {
+		g.Get(k)
+	}
+
+	pool := cache.NewHttpPool("http://a")
+	pool.Set("http://a", "http://b")
+	server := httptest.NewServer(cache.NewMetrics(pool))
+	defer server.Close()
+
+	res, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	text := string(body)
+
+	for _, expect := range []string{
+		`cache_gets_total{group="metrics"} 4`,
+		`cache_hits_total{group="metrics"} 1`,
+		`cache_misses_total{group="metrics"} 3`,
+		`cache_local_loads_total{group="metrics"} 3`,
+		`cache_evictions_total{group="metrics"} 1`,
+		`cache_items{group="metrics"} 2`,
+		`cache_load_duration_seconds_bucket{group="metrics",le="+Inf"} 3`,
+		`cache_load_duration_seconds_count{group="metrics"} 3`,
+		`cache_peer_get_duration_seconds_count{group="metrics"} 0`,
+		`cache_ring_peers 2`,
+		`cache_ring_virtual_nodes 100`,
+		`# TYPE cache_load_duration_seconds histogram`,
+	} {
+		if !strings.Contains(text, expect+"\n") {
+			t.Errorf("metrics missing %q", expect)
+		}
+	}
+
+	// 每一行要么是注释，要么是 name{labels} value
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) != 2 {
+			t.Errorf("malformed sample line %q", line)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"cache"
+	pb "cache/cachepb"
+)
+
+type recordObserver struct {
+	cache.NoopObserver
+	mtx     sync.Mutex
+	events  []string
+	onEvict func()
+}
+
+func (o *recordObserver) record(format string, v ...interface{}) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, v...))
+}
+
+func (o *recordObserver) take() []string {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	events := o.events
+	o.events = nil
+	return events
+}
+
+func (o *recordObserver) OnGetHit(group, key string)    { o.record("hit %s", key) }
+func (o *recordObserver) OnGetMiss(group, key string)   { o.record("miss %s", key) }
+func (o *recordObserver) OnLoadStart(group, key string) { o.record("load %s", key) }
+func (o *recordObserver) OnLoadEnd(group, key string, d time.Duration, err error) {
+	o.record("loaded %s %v", key, err)
+}
+func (o *recordObserver) OnPeerPick(group, key, peer string) { o.record("pick %s %s", key, peer) }
+func (o *recordObserver) OnPeerFetch(group, key, peer string, d time.Duration, err error) {
+	o.record("fetch %s %s %v", key, peer, err)
+}
+func (o *recordObserver) OnEvict(group, key string, reason cache.EvictReason) {
+	o.record("evict %s %s", key, reason)
+	if o.onEvict != nil {
+		o.onEvict()
+	}
+}
+
+// fakePeers 所有 key 都分配给同一个节点
+type fakePeers struct{ err error }
+
+func (p *fakePeers) PickPeer(key string) (cache.PeerGetter, bool) { return p, true }
+func (p *fakePeers) GetAll() []cache.PeerGetter                   { return []cache.PeerGetter{p} }
+func (p *fakePeers) String() string                               { return "fake" }
+func (p *fakePeers) Get(in *pb.Request, out *pb.Response) error {
+	if p.err != nil {
+		return p.err
+	}
+	out.Value = []byte("peer-" + in.Key)
+	return nil
+}
+func (p *fakePeers) Set(in *pb.SetRequest) error        { return nil }
+func (p *fakePeers) Remove(in *pb.Request) error        { return nil }
+func (p *fakePeers) SetGeneration(in *pb.Request) error { return nil }
+func (p *fakePeers) InvalidateTag(in *pb.Request) error { return nil }
+
+func TestObserverLocal(t *testing.T) {
+	g := cache.ReplaceGroup("observer", 20, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "bad" {
+				return nil, cache.ErrNotFound
+			}
+			return []byte("value"), nil
+		}))
+	o := &recordObserver{}
+	g.RegisterObserver(o)
+	// 回调中再次访问 group，持有锁时会死锁
+	o.onEvict = func() { g.Remove("nobody") }
+
+	g.Get("key01")
+	g.Get("key01")
+	g.Get("bad")
+	expect := []string{
+		"miss key01", "load key01", "loaded key01 <nil>",
+		"hit key01",
+		"miss bad", "load bad", "loaded bad not found",
+	}
+	if got := o.take(); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %q, got %q", expect, got)
+	}
+
+	g.Get("key02")
+	g.Get("key03") // 淘汰 key01
+	g.Set("key04", []byte("value"), time.Now().Add(-time.Second))
+	g.Get("key04")
+	g.Remove("key03")
+
+	var evicts []string
+	for _, e := range o.take() {
+		if len(e) > 5 && e[:5] == "evict" {
+			evicts = append(evicts, e)
+		}
+	}
+	expect = []string{"evict key01 capacity", "evict key02 capacity", "evict key04 expired", "evict key03 removed"}
+	if !reflect.DeepEqual(evicts, expect) {
+		t.Fatalf("expect %q, got %q", expect, evicts)
+	}
+}
+
+func TestObserverPeer(t *testing.T) {
+	g := cache.ReplaceGroup("observer-peer", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("local"), nil
+		}))
+	peers := &fakePeers{}
+	g.RegisterPeers(peers)
+	o := &recordObserver{}
+	g.RegisterObserver(o)
+
+	g.Get("a")
+	peers.err = errors.New("down")
+	g.Get("b")
+	expect := []string{
+		"miss a", "load a", "pick a fake", "fetch a fake <nil>", "loaded a <nil>",
+		"miss b", "load b", "pick b fake", "fetch b fake down", "loaded b <nil>",
+	}
+	if got := o.take(); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %q, got %q", expect, got)
+	}
+}
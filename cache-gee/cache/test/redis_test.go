@@ -0,0 +1,158 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cache"
+	"cache/resp"
+)
+
+type respClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *resp.Reader
+	w    *resp.Writer
+}
+
+func dialRedis(t *testing.T, addr string) *respClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &respClient{t: t, conn: conn, r: resp.NewReader(conn), w: resp.NewWriter(conn)}
+}
+
+// do 发送一条命令并读取回复
+func (c *respClient) do(args ...string) interface{} {
+	c.w.WriteCommand(args...)
+	if err := c.w.Flush(); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.reply()
+}
+
+func (c *respClient) reply() interface{} {
+	v, err := c.r.ReadReply()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+func startRedis(t *testing.T) (*cache.RedisServer, string) {
+	cache.ReplaceGroup("redis", 2<<10, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s: %w", key, cache.ErrNotFound)
+		}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := cache.NewRedisServer()
+	go server.Serve(l)
+	return server, l.Addr().String()
+}
+
+func TestRedisCommands(t *testing.T) {
+	server, addr := startRedis(t)
+	defer server.Close()
+	c := dialRedis(t, addr)
+	defer c.conn.Close()
+
+	cases := []struct {
+		args   []string
+		expect interface{}
+	}{
+		{[]string{"PING"}, "PONG"},
+		{[]string{"GET", "redis:Tom"}, "630"},
+		{[]string{"GET", "redis:nobody"}, nil},
+		{[]string{"SET", "redis:k", "v"}, "OK"},
+		{[]string{"GET", "redis:k"}, "v"},
+		{[]string{"TTL", "redis:k"}, int64(-1)},
+		{[]string{"SET", "redis:t", "v", "EX", "100"}, "OK"},
+		{[]string{"TTL", "redis:t"}, int64(100)},
+		{[]string{"TTL", "redis:nobody"}, int64(-2)},
+		{[]string{"EXISTS", "redis:k", "redis:nobody", "redis:Sam"}, int64(2)},
+		{[]string{"MGET", "redis:Tom", "redis:nobody", "redis:k"}, []interface{}{[]byte("630"), nil, []byte("v")}},
+		{[]string{"DEL", "redis:k", "redis:nobody"}, int64(1)},
+		{[]string{"DEL", "redis:k"}, int64(0)},
+		{[]string{"GET", "redis:k"}, nil},
+		{[]string{"GET", "nogroup"}, resp.Error("ERR key must be in the form group:key")},
+		{[]string{"FLUSHALL"}, resp.Error("ERR unknown command 'flushall'")},
+	}
+	for _, tc := range cases {
+		if got := c.do(tc.args...); !reflect.DeepEqual(got, tc.expect) {
+			t.Fatalf("%v: expect %#v, got %#v", tc.args, tc.expect, got)
+		}
+	}
+
+	info, _ := c.do("INFO").(string)
+	if !strings.Contains(info, "redis:keys=") {
+		t.Fatalf("INFO missing keyspace: %q", info)
+	}
+}
+
+func TestRedisPipeline(t *testing.T) {
+	server, addr := startRedis(t)
+	defer server.Close()
+	c := dialRedis(t, addr)
+	defer c.conn.Close()
+
+	// 一次性写入多条命令，回复需要按顺序返回
+	c.w.WriteCommand("SET", "redis:p", "1")
+	c.w.WriteCommand("GET", "redis:p")
+	c.w.WriteCommand("GET", "redis:Jack")
+	c.w.WriteCommand("PING", "hello")
+	c.w.Flush()
+	for _, expect := range []interface{}{"OK", "1", "589", "hello"} {
+		if got := c.reply(); got != expect {
+			t.Fatalf("expect %#v, got %#v", expect, got)
+		}
+	}
+
+	// inline command
+	c.conn.Write([]byte("PING\r\n"))
+	if got := c.reply(); got != "PONG" {
+		t.Fatalf("inline PING: %#v", got)
+	}
+}
+
+func TestRedisProtocolLimits(t *testing.T) {
+	server, addr := startRedis(t)
+	defer server.Close()
+
+	// 超出上限的 multi bulk 长度直接拒绝，不会按照声明的长度分配内存
+	c := dialRedis(t, addr)
+	c.conn.Write([]byte("*268435456\r\n"))
+	if got := c.reply(); got != resp.Error("ERR Protocol error") {
+		t.Fatalf("oversized multi bulk: %#v", got)
+	}
+	if _, err := c.r.ReadReply(); err == nil {
+		t.Fatal("expect connection closed after protocol error")
+	}
+	c.conn.Close()
+
+	// 声明的 bulk 长度很大但是数据没有到达，读取到的部分之后连接断开
+	r := resp.NewReader(strings.NewReader("*1\r\n$536870000\r\nshort"))
+	if _, err := r.ReadCommand(); err == nil {
+		t.Fatal("expect error for truncated bulk")
+	}
+
+	// 节点仍然可以正常处理其他连接
+	c = dialRedis(t, addr)
+	defer c.conn.Close()
+	if got := c.do("PING"); got != "PONG" {
+		t.Fatalf("ping after protocol error: %#v", got)
+	}
+}
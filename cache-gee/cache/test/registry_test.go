@@ -0,0 +1,88 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"cache"
+	pb "cache/cachepb"
+)
+
+func constGetter(value string) cache.Getter {
+	return cache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(value), nil
+	})
+}
+
+func TestRegistryLifecycle(t *testing.T) {
+	r := cache.NewRegistry()
+	g, err := r.NewGroup("users", 0, constGetter("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.NewGroup("users", 0, constGetter("v2")); !errors.Is(err, cache.ErrGroupExists) {
+		t.Fatalf("expect ErrGroupExists, got %v", err)
+	}
+	if r.GetGroup("users") != g {
+		t.Fatal("duplicate NewGroup should keep the original group")
+	}
+
+	replaced := r.ReplaceGroup("users", 0, constGetter("v2"))
+	if v, _ := r.GetGroup("users").Get("k"); r.GetGroup("users") != replaced || v.String() != "v2" {
+		t.Fatal("ReplaceGroup should register the new group")
+	}
+
+	if !r.DeleteGroup("users") || r.GetGroup("users") != nil {
+		t.Fatal("DeleteGroup failed")
+	}
+	if r.DeleteGroup("users") {
+		t.Fatal("deleting a missing group should return false")
+	}
+	if cache.GetGroup("users") != nil {
+		t.Fatal("registries should be isolated from DefaultRegistry")
+	}
+}
+
+func TestSetCacheBytes(t *testing.T) {
+	g, err := cache.NewRegistry().NewGroup("resize", 0, constGetter("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		g.Get(fmt.Sprintf("key%02d", i))
+	}
+	g.Get("key00") // key00 变为最近使用
+
+	// 每一项 10 bytes，只保留最近使用的 3 项
+	g.SetCacheBytes(30)
+	loads := g.Stats.LocalLoads.Get()
+	for _, k := range []string{"key00", "key09", "key08"} {
+		g.Get(k)
+	}
+	if g.Stats.LocalLoads.Get() != loads {
+		t.Fatal("recently used keys should survive the resize")
+	}
+	g.Get("key01")
+	if g.Stats.LocalLoads.Get() != loads+1 {
+		t.Fatal("key01 should have been evicted by the resize")
+	}
+}
+
+func TestRegistryHttpPool(t *testing.T) {
+	r := cache.NewRegistry()
+	if _, err := r.NewGroup("tenant", 0, constGetter("isolated")); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(cache.NewHttpPoolOpts("server", &cache.HttpPoolOptions{Registry: r}))
+	defer server.Close()
+
+	client := cache.NewHttpPool("client")
+	client.Set(server.URL)
+	peer, _ := client.PickPeer("k")
+	res := &pb.Response{}
+	if err := peer.Get(&pb.Request{Group: "tenant", Key: "k"}, res); err != nil || string(res.Value) != "isolated" {
+		t.Fatalf("get from registry failed: %v", err)
+	}
+}
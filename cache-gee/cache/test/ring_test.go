@@ -0,0 +1,91 @@
+package test
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cache"
+	"cache/consistenthash"
+)
+
+func TestRingLayout(t *testing.T) {
+	m := consistenthash.New(50, nil)
+	m.Add("a", "b", "c")
+
+	var share float64
+	for _, node := range m.Nodes() {
+		if len(node.VNodes) != 50 {
+			t.Fatalf("%s: expect 50 vnodes, got %d", node.Name, len(node.VNodes))
+		}
+		share += node.Share
+	}
+	if math.Abs(share-1) > 1e-9 {
+		t.Fatalf("shares should add up to 1, got %v", share)
+	}
+
+	// Moved 与按 key 抽样统计的结果一致
+	next := consistenthash.New(50, nil)
+	next.Add("a", "b", "c", "d")
+	moved, total := 0, 100000
+	for i := 0; i < total; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if m.Get(key) != next.Get(key) {
+			moved++
+		}
+	}
+	expect := float64(moved) / float64(total)
+	if got := m.Moved(next); math.Abs(got-expect) > 0.01 {
+		t.Fatalf("expect about %.3f keys moved, got %.3f", expect, got)
+	}
+	for _, node := range next.Nodes() {
+		if node.Name == "d" && math.Abs(node.Share-m.Moved(next)) > 1e-9 {
+			t.Fatalf("keys moved to d should equal d's share: %v %v", node.Share, m.Moved(next))
+		}
+	}
+	if m.Moved(m) != 0 {
+		t.Fatal("same ring should not move keys")
+	}
+}
+
+func TestRingHandler(t *testing.T) {
+	pool := cache.NewHttpPool("http://a")
+	pool.Set("http://a", "http://b", "http://c")
+	server := httptest.NewServer(pool.RingHandler())
+	defer server.Close()
+
+	var ring cache.RingInfo
+	adminDo(t, http.MethodGet, server.URL, &ring)
+	if ring.Self != "http://a" || ring.VNodes != 150 || len(ring.Peers) != 3 || ring.Peers[0].Name != "http://a" {
+		t.Fatalf("unexpected ring: %+v", ring)
+	}
+
+	var owner cache.KeyOwner
+	adminDo(t, http.MethodGet, server.URL+"?key=user:1&n=2", &owner)
+	if len(owner.Replicas) != 2 || owner.Replicas[0] != owner.Owner || owner.Replicas[0] == owner.Replicas[1] {
+		t.Fatalf("unexpected owner: %+v", owner)
+	}
+	if loc := pool.Locate("user:1", 3); loc.Owner != owner.Owner || len(loc.Replicas) != 3 {
+		t.Fatalf("unexpected locate: %+v", loc)
+	}
+
+	// 删除一个节点时需要迁移的正好是它负责的部分
+	var change cache.RingChange
+	adminDo(t, http.MethodGet, server.URL+"?remove=http://b", &change)
+	if len(change.Peers) != 2 || math.Abs(change.Moved-ring.Peers[1].Share) > 1e-9 {
+		t.Fatalf("unexpected change: %+v, b share %v", change, ring.Peers[1].Share)
+	}
+	adminDo(t, http.MethodGet, server.URL+"?add=http://d", &change)
+	if len(change.Peers) != 4 || change.Moved <= 0 || change.Moved >= 0.5 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	if code := adminDo(t, http.MethodGet, server.URL+"?remove=http://x", nil); code != http.StatusBadRequest {
+		t.Fatalf("expect 400 for unknown peer, got %d", code)
+	}
+	// 模拟不会修改当前的哈希环
+	if after := pool.Ring(); after.VNodes != 150 {
+		t.Fatalf("ring changed: %+v", after)
+	}
+}
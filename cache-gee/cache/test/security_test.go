@@ -0,0 +1,195 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"cache"
+	pb "cache/cachepb"
+)
+
+// newCert 生成一张由 parent 签发的证书，parent 为 nil 时生成自签名的 CA
+func newCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (tls.Certificate, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf, key
+}
+
+func newSecureGroup(name string) {
+	cache.ReplaceGroup(name, 2<<10, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+}
+
+func peerGet(t *testing.T, pool *cache.HttpPool, group, key string) (string, error) {
+	peer, ok := pool.PickPeer(key)
+	if !ok {
+		t.Fatal("pick peer failed")
+	}
+	res := &pb.Response{}
+	err := peer.Get(&pb.Request{Group: group, Key: key}, res)
+	return string(res.Value), err
+}
+
+func TestHttpPoolMutualTLS(t *testing.T) {
+	newSecureGroup("tls")
+
+	_, ca, caKey := newCert(t, "test ca", nil, nil)
+	serverCert, _, _ := newCert(t, "server", ca, caKey)
+	clientCert, _, _ := newCert(t, "client", ca, caKey)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	pool := cache.NewHttpPoolOpts("server", &cache.HttpPoolOptions{
+		TLS: &cache.TLSOptions{Certificate: serverCert, RootCAs: roots, Mutual: true},
+	})
+	server := httptest.NewUnstartedServer(pool)
+	server.TLS = pool.ServerTLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	client := cache.NewHttpPoolOpts("client", &cache.HttpPoolOptions{
+		TLS: &cache.TLSOptions{Certificate: clientCert, RootCAs: roots, Mutual: true},
+	})
+	client.Set(server.URL)
+	if v, err := peerGet(t, client, "tls", "k"); err != nil || v != "v-k" {
+		t.Fatalf("mutual tls get failed: %v %q", err, v)
+	}
+
+	// 信任 CA 但没有客户端证书
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	res, err := anonymous.Get(server.URL + "/distributed_cache/tls/k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect 401 without client cert, got %d", res.StatusCode)
+	}
+}
+
+func TestHttpPoolHMAC(t *testing.T) {
+	newSecureGroup("hmac")
+
+	var captured *http.Request
+	pool := cache.NewHttpPoolOpts("server", &cache.HttpPoolOptions{Secret: []byte("s3cret")})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if captured == nil {
+			captured = r.Clone(r.Context())
+		}
+		pool.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := cache.NewHttpPoolOpts("client", &cache.HttpPoolOptions{Secret: []byte("s3cret")})
+	client.Set(server.URL)
+	if v, err := peerGet(t, client, "hmac", "k"); err != nil || v != "v-k" {
+		t.Fatalf("signed get failed: %v %q", err, v)
+	}
+
+	wrong := cache.NewHttpPoolOpts("client", &cache.HttpPoolOptions{Secret: []byte("wrong")})
+	wrong.Set(server.URL)
+	if _, err := peerGet(t, wrong, "hmac", "k"); err == nil {
+		t.Fatal("request signed with wrong secret should fail")
+	}
+
+	expect401 := func(req *http.Request) {
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expect 401, got %d", res.StatusCode)
+		}
+	}
+
+	unsigned, _ := http.NewRequest(http.MethodGet, server.URL+"/distributed_cache/hmac/k", nil)
+	expect401(unsigned)
+
+	// 原样重放第一次成功的请求
+	replay, _ := http.NewRequest(http.MethodGet, server.URL+captured.URL.RequestURI(), nil)
+	replay.Header = captured.Header.Clone()
+	expect401(replay)
+}
+
+// 校验签名之前读取的请求体有上限，不会缓存任意大小的请求体
+func TestHttpPoolHMACBodyLimit(t *testing.T) {
+	newSecureGroup("hmac-limit")
+	opts := &cache.HttpPoolOptions{Secret: []byte("s3cret"), MaxBodyBytes: 1 << 10}
+	server := httptest.NewServer(cache.NewHttpPoolOpts("server", opts))
+	defer server.Close()
+	client := cache.NewHttpPoolOpts("client", opts)
+	client.Set(server.URL)
+
+	peer, _ := client.PickPeer("k")
+	wp := peer.(cache.WritePeerGetter)
+	if err := wp.Set(&pb.SetRequest{Group: "hmac-limit", Key: "k", Value: []byte("small")}); err != nil {
+		t.Fatalf("small set: %v", err)
+	}
+	if err := wp.Set(&pb.SetRequest{Group: "hmac-limit", Key: "k", Value: make([]byte, 4<<10)}); err == nil {
+		t.Fatal("expect set over MaxBodyBytes to fail")
+	}
+
+	// 没有 Content-Length 的请求体读到上限就拒绝
+	body := io.LimitReader(zeroReader{}, 64<<20)
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/distributed_cache/hmac-limit/k", ioutil.NopCloser(body))
+	req.Header.Set("X-Cache-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set("X-Cache-Nonce", "n")
+	req.Header.Set("X-Cache-Signature", "bogus")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", res.StatusCode)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
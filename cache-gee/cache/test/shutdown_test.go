@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// keyOwnedBy 返回 owner 为 nd 的 key
+func keyOwnedBy(nodes []*node, nd *node) string {
+	for i := 0; ; i++ {
+		if k := fmt.Sprintf("key-%d", i); nodes[0].pool.Locate(k, 1).Owner == nd.server.URL {
+			return k
+		}
+	}
+}
+
+func TestShutdownHandOff(t *testing.T) {
+	var mtx sync.Mutex
+	loads := 0
+	getter := cache.GetterFunc(func(key string) ([]byte, error) {
+		mtx.Lock()
+		loads++
+		mtx.Unlock()
+		return []byte("v-" + key), nil
+	})
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{HandOffHotKeys: true})
+	groups := newClusterGroup(t, nodes, "shutdown", getter)
+	for _, g := range groups {
+		g.SetHotKeys(&cache.HotKeyOptions{Threshold: 3})
+	}
+
+	leaving := nodes[2]
+	key := keyOwnedBy(nodes, leaving)
+	for i := 0; i < 5; i++ {
+		groups[2].Get(key)
+	}
+	if !groups[2].IsHot(key) || loads != 1 {
+		t.Fatalf("expect %s to be hot after 1 load, loads=%d", key, loads)
+	}
+
+	if err := leaving.pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 其他节点已经把它从哈希环中删除
+	for _, nd := range nodes[:2] {
+		for _, peer := range nd.pool.Ring().Peers {
+			if peer.Name == leaving.server.URL {
+				t.Fatalf("%s still in the ring of %s", peer.Name, nd.server.URL)
+			}
+		}
+	}
+	// 热点 key 已经转移到新的 owner，不需要重新加载
+	for _, g := range groups[:2] {
+		if v, err := g.Get(key); err != nil || v.String() != "v-"+key {
+			t.Fatalf("get %s: %v %q", key, err, v)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("expect hot key handed off, loads=%d", loads)
+	}
+
+	// 下线之后拒绝其他节点的请求
+	res, err := http.Get(leaving.server.URL + "/distributed_cache/shutdown/" + key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expect 503 after shutdown, got %d", res.StatusCode)
+	}
+}
+
+func TestShutdownDrain(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	getter := cache.GetterFunc(func(key string) ([]byte, error) {
+		started <- struct{}{}
+		<-release
+		return []byte("v-" + key), nil
+	})
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "drain", getter)
+	key := keyOwnedBy(nodes, nodes[1])
+
+	// nodes[0] 的请求正在 owner 上加载
+	got := make(chan error, 1)
+	go func() {
+		v, err := groups[0].Get(key)
+		if err == nil && v.String() != "v-"+key {
+			err = fmt.Errorf("unexpected value %q", v)
+		}
+		got <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := nodes[1].pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect shutdown to wait for the in-flight load, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- nodes[1].pool.Shutdown(context.Background()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned before the load finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// 正在处理的请求正常返回
+	if err := <-got; err != nil {
+		t.Fatal(err)
+	}
+}
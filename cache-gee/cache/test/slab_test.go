@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"cache"
+	"cache/slab"
+)
+
+func slabValueOf(i int) []byte {
+	return bytes.Repeat([]byte{byte('a' + i%26)}, 10+i%300)
+}
+
+func TestSlabStorage(t *testing.T) {
+	g := cache.ReplaceGroup("slab", 64<<10, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("db-" + key), nil
+		}))
+	g.EnableSlabStorage(slab.Options{SlabSize: 16 << 10})
+
+	if err := g.Set("first", slabValueOf(0), time.Time{}, "tag"); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := g.Get("first")
+
+	// 写入远超 cacheBytes 的数据，被淘汰的空间会被复用
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("k%d", i)
+		g.Set(key, slabValueOf(i), time.Time{})
+		if v, err := g.Get(key); err != nil || !bytes.Equal(v.ByteSlice(), slabValueOf(i)) {
+			t.Fatalf("%s: %v %q", key, err, v)
+		}
+	}
+	if !bytes.Equal(first.ByteSlice(), slabValueOf(0)) {
+		t.Fatal("ByteView changed after its slab region was reused")
+	}
+	stats := g.SlabStats()
+	if stats.Slabs == 0 || stats.SlabBytes > 256<<10 || stats.UsedBytes > stats.SlabBytes {
+		t.Fatalf("unexpected slab stats: %+v", stats)
+	}
+
+	// 超过最大 size class 的值保存在堆上
+	big := bytes.Repeat([]byte("x"), 8<<10)
+	g.Set("big", big, time.Time{})
+	if v, _ := g.Get("big"); !bytes.Equal(v.ByteSlice(), big) {
+		t.Fatal("big value mismatch")
+	}
+
+	g.Set("tagged", []byte("v"), time.Time{}, "tag")
+	g.InvalidateTag("tag")
+	if v, _ := g.Get("tagged"); v.String() != "db-tagged" {
+		t.Fatalf("tagged value not invalidated: %q", v)
+	}
+	g.Set("expired", []byte("v"), time.Now().Add(-time.Second))
+	if v, _ := g.Get("expired"); v.String() != "db-expired" {
+		t.Fatalf("expired value returned: %q", v)
+	}
+}
+
+// 命中时 ByteView 引用 slab 中的空间，不会比堆上的值多分配
+func TestSlabGetNoCopy(t *testing.T) {
+	allocs := func(useSlabs bool) float64 {
+		g := cache.ReplaceGroup("slab-nocopy", 0, cache.GetterFunc(
+			func(key string) ([]byte, error) {
+				return nil, cache.ErrNotFound
+			}))
+		defer cache.DeleteGroup("slab-nocopy")
+		if useSlabs {
+			g.EnableSlabStorage(slab.Options{})
+		}
+		g.Set("k", make([]byte, 1000), time.Time{})
+		return testing.AllocsPerRun(100, func() { g.Get("k") })
+	}
+	if heap, slabs := allocs(false), allocs(true); slabs > heap {
+		t.Fatalf("expect slab hits to allocate no more than heap hits: %v > %v", slabs, heap)
+	}
+}
+
+func TestSlabRelease(t *testing.T) {
+	r := cache.NewRegistry()
+	g, _ := r.NewGroup("slab-release", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, cache.ErrNotFound
+		}))
+	g.EnableSlabStorage(slab.Options{SlabSize: 4 << 10})
+	for i := 0; i < 1000; i++ {
+		g.Set(fmt.Sprintf("k%04d", i), make([]byte, 50), time.Time{})
+	}
+	full := g.SlabStats()
+
+	// 全部删除之后只保留正在切分的 slab
+	for i := 0; i < 1000; i++ {
+		g.Remove(fmt.Sprintf("k%04d", i))
+	}
+	if st := g.SlabStats(); st.Slabs != 1 || st.UsedBytes != 0 || full.Slabs < 10 {
+		t.Fatalf("empty slabs not released: before %+v, after %+v", full, st)
+	}
+
+	// 空闲的 slab 空间在 MemoryBudget 分配时计算在内，没有容量压力的 group 最多分到两倍
+	g.Set("a", make([]byte, 50), time.Time{})
+	r.SetMemoryBudget(&cache.MemoryBudget{TotalBytes: 1 << 20, Interval: time.Hour})
+	defer r.SetMemoryBudget(nil)
+	if got, expect := g.CacheBytes(), 2*(g.SlabStats().SlabBytes+1); got != expect {
+		t.Fatalf("expect %d bytes allocated, got %d", expect, got)
+	}
+}
+
+// benchmarkGet 测量缓存命中时 Get 的耗时和分配，slab 中的值命中时不会拷贝，分配与堆上的值相同
+func benchmarkGet(b *testing.B, useSlabs bool) {
+	const items = 1000
+	g := cache.ReplaceGroup("get-bench", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, cache.ErrNotFound
+		}))
+	defer cache.DeleteGroup("get-bench")
+	if useSlabs {
+		g.EnableSlabStorage(slab.Options{})
+	}
+	keys := make([]string, items)
+	value := make([]byte, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		g.Set(keys[i], value, time.Time{})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := g.Get(keys[i%items]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetHeapValues(b *testing.B) { benchmarkGet(b, false) }
+func BenchmarkGetSlabValues(b *testing.B) { benchmarkGet(b, true) }
+
+// benchmarkGC 写入大量小的缓存值之后测量一次完整 GC 的耗时
+// ns/op 是一次 runtime.GC 的耗时，主要是标记存活对象；pause-ns/gc 是其中 STW 的时间
+func benchmarkGC(b *testing.B, useSlabs bool) {
+	const items = 500000
+	g := cache.ReplaceGroup("gc-bench", 0, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, cache.ErrNotFound
+		}))
+	defer cache.DeleteGroup("gc-bench")
+	if useSlabs {
+		g.EnableSlabStorage(slab.Options{})
+	}
+	value := make([]byte, 100)
+	for i := 0; i < items; i++ {
+		g.Set(fmt.Sprintf("key-%d", i), value, time.Time{})
+	}
+	runtime.GC()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "pause-ns/gc")
+	b.ReportMetric(float64(after.HeapObjects), "heap-objects")
+	runtime.KeepAlive(g)
+}
+
+func BenchmarkGCHeapValues(b *testing.B) { benchmarkGC(b, false) }
+func BenchmarkGCSlabValues(b *testing.B) { benchmarkGC(b, true) }
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// flakyDB down 为 true 时所有的读取都失败
+type flakyDB struct{ down bool }
+
+func (db *flakyDB) Get(key string) ([]byte, error) {
+	if db.down {
+		return nil, errors.New("db is down")
+	}
+	if key == "missing" {
+		return nil, fmt.Errorf("%s: %w", key, cache.ErrNotFound)
+	}
+	return []byte("db-" + key), nil
+}
+
+func TestStaleOnError(t *testing.T) {
+	db := &flakyDB{}
+	// 每个值 len(key)+len(value)=5+8 bytes，只能放下两个
+	g := cache.ReplaceGroup("stale", 26, db)
+	g.SetStaleOnError(&cache.StaleOptions{Grace: time.Hour})
+
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		g.Get(key)
+	}
+	g.Set("key-4", []byte("set-key4"), time.Now().Add(50*time.Millisecond))
+	g.Set("key-5", []byte("set-key5"), time.Time{}, "t")
+	g.Get("key-2")
+	g.Get("key-3")
+	g.Remove("key-3")
+	time.Sleep(100 * time.Millisecond)
+
+	db.down = true
+	// 被淘汰以及过期的值仍然可以返回
+	for key, expect := range map[string]string{"key-1": "db-key-1", "key-4": "set-key4", "key-5": "set-key5"} {
+		v, err := g.Get(key)
+		if err != nil || v.String() != expect || !v.Stale() {
+			t.Fatalf("get %s: %v %q stale=%v", key, err, v, v.Stale())
+		}
+	}
+	if n := g.Stats.StaleServed.Get(); n != 3 {
+		t.Fatalf("expect 3 stale values served, got %d", n)
+	}
+	// Remove 删除的以及没有缓存过的 key 仍然返回错误
+	for _, key := range []string{"key-3", "key-6"} {
+		if _, err := g.Get(key); err == nil {
+			t.Fatalf("expect error for %s", key)
+		}
+	}
+
+	// InvalidateTag 以及 BumpGeneration 之后旧值不再返回
+	g.InvalidateTag("t")
+	if _, err := g.Get("key-5"); err == nil {
+		t.Fatal("expect invalidated value not to be served")
+	}
+	g.BumpGeneration()
+	if _, err := g.Get("key-1"); err == nil {
+		t.Fatal("expect value of old generation not to be served")
+	}
+
+	// 恢复之后返回新的值
+	db.down = false
+	if v, err := g.Get("key-1"); err != nil || v.Stale() || v.String() != "db-key-1" {
+		t.Fatalf("get after recovery: %v %q stale=%v", err, v, v.Stale())
+	}
+}
+
+func TestStaleNotFound(t *testing.T) {
+	db := &flakyDB{}
+	g := cache.ReplaceGroup("stale-not-found", 0, db)
+	g.SetStaleOnError(&cache.StaleOptions{})
+	g.Set("missing", []byte("old"), time.Now().Add(time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+
+	// 数据源明确返回不存在时不使用旧值
+	if _, err := g.Get("missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expect ErrNotFound, got %v", err)
+	}
+}
+
+func TestStaleFromPeer(t *testing.T) {
+	db := &flakyDB{}
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "stale-peer", db)
+	for _, g := range groups {
+		g.SetStaleOnError(&cache.StaleOptions{})
+	}
+
+	key := ""
+	for i := 0; key == ""; i++ {
+		if k := fmt.Sprintf("key-%d", i); nodes[0].pool.Locate(k, 1).Owner == nodes[1].server.URL {
+			key = k
+		}
+	}
+	if err := groups[0].Set(key, []byte("old"), time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// owner 返回的旧值带有标记
+	db.down = true
+	v, err := groups[0].Get(key)
+	if err != nil || v.String() != "old" || !v.Stale() {
+		t.Fatalf("get from peer: %v %q stale=%v", err, v, v.Stale())
+	}
+	s, err := groups[0].GetStream(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(s)
+	s.Close()
+	if string(b) != "old" || !s.Stale {
+		t.Fatalf("stream from peer: %q stale=%v", b, s.Stale)
+	}
+}
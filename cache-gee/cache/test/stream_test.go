@@ -0,0 +1,140 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// bigValue 生成 n 字节、每个 key 不同的内容
+func bigValue(key string, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = key[i%len(key)] + byte(i/251)
+	}
+	return b
+}
+
+func TestGetStream(t *testing.T) {
+	const size = 3<<20 + 123
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "stream", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "small" {
+				return []byte("v-small"), nil
+			}
+			return bigValue(key, size), nil
+		}))
+
+	read := func(g *cache.Group, key string) []byte {
+		r, err := g.GetStream(key)
+		if err != nil {
+			t.Fatalf("%s GetStream %s: %v", g.Name(), key, err)
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// owner 和非 owner 上得到的内容都相同
+	for _, key := range []string{"a", "b", "c", "d"} {
+		for i, g := range groups {
+			if b := read(g, key); !bytes.Equal(b, bigValue(key, size)) {
+				t.Fatalf("node %d stream %s: got %d bytes", i, key, len(b))
+			}
+			if v, err := g.Get(key); err != nil || !bytes.Equal(v.ByteSlice(), bigValue(key, size)) || v.Len() != size {
+				t.Fatalf("node %d get %s: %v len=%d", i, key, err, v.Len())
+			}
+		}
+	}
+	for _, g := range groups {
+		if b := read(g, "small"); string(b) != "v-small" {
+			t.Fatalf("small value: %q", b)
+		}
+	}
+
+	// 超过阈值时直接返回缓存值，否则仍然是 protobuf
+	for key, expect := range map[string]string{"a": "application/x-cache-value", "small": "application/octet-stream"} {
+		res, err := http.Get(nodes[0].server.URL + "/distributed_cache/stream/" + key + "?stream=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if ct := res.Header.Get("Content-Type"); ct != expect {
+			t.Fatalf("%s: expect content type %s, got %s", key, expect, ct)
+		}
+		if key == "a" && !bytes.Equal(body, bigValue(key, size)) {
+			t.Fatalf("streamed body mismatch: %d bytes", len(body))
+		}
+	}
+}
+
+// slowTransport 每次读取响应体之前等待 delay，每次最多读取 4KB
+type slowTransport struct {
+	delay int64 // time.Duration，原子读写
+}
+
+func (t *slowTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := http.DefaultTransport.RoundTrip(r)
+	if err == nil {
+		res.Body = &slowBody{ReadCloser: res.Body, t: t}
+	}
+	return res, err
+}
+
+type slowBody struct {
+	io.ReadCloser
+	t *slowTransport
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	time.Sleep(time.Duration(atomic.LoadInt64(&b.t.delay)))
+	if len(p) > 4<<10 {
+		p = p[:4<<10]
+	}
+	return b.ReadCloser.Read(p)
+}
+
+func TestGetStreamTimeout(t *testing.T) {
+	const size = 64 << 10
+	transport := &slowTransport{delay: int64(10 * time.Millisecond)}
+	nodes := newCluster(t, 2, cache.HttpPoolOptions{Transport: transport, Timeout: 100 * time.Millisecond, StreamThreshold: 1 << 10})
+	groups := newClusterGroup(t, nodes, "stream-timeout", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return bigValue(key, size), nil
+		}))
+	key := keyOwnedBy(nodes, nodes[1])
+
+	// 读取的总时间超过 Timeout，但是每次读取都没有超时
+	r, err := groups[0].GetStream(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(b, bigValue(key, size)) {
+		t.Fatalf("slow stream: %v, got %d bytes", err, len(b))
+	}
+
+	// 一次读取等待超过 Timeout 时中断
+	atomic.StoreInt64(&transport.delay, int64(150*time.Millisecond))
+	groups[1].Remove(key)
+	r, err = groups[0].GetStream(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Fatal("expect stalled stream to time out")
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// taggedDB 以 user:{id}:{field} 为 key，每个值都带上 user:{id} tag
+type taggedDB struct{ loads int }
+
+func (db *taggedDB) Get(key string) ([]byte, error) {
+	v, _, err := db.GetTags(key)
+	return v, err
+}
+
+func (db *taggedDB) GetTags(key string) ([]byte, []string, error) {
+	db.loads++
+	parts := strings.SplitN(key, ":", 3)
+	return []byte("db-" + key), []string{parts[0] + ":" + parts[1]}, nil
+}
+
+func TestInvalidateTag(t *testing.T) {
+	db := &taggedDB{}
+	g := cache.ReplaceGroup("tags", 2<<10, db)
+
+	for _, key := range []string{"user:1:profile", "user:1:avatar", "user:2:profile"} {
+		g.Get(key)
+	}
+	if err := g.InvalidateTag("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := g.Stats.Removes.Get(); n != 2 {
+		t.Fatalf("expect 2 keys removed, got %d", n)
+	}
+	for _, key := range []string{"user:1:profile", "user:1:avatar", "user:2:profile"} {
+		g.Get(key)
+	}
+	if db.loads != 5 {
+		t.Fatalf("expect only user:1 to reload, loads=%d", db.loads)
+	}
+}
+
+func TestTagIndexEviction(t *testing.T) {
+	// 每个值 len(key)+len(value)=10 bytes，只能放下两个
+	g := cache.ReplaceGroup("tag-evict", 20, cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value"), nil
+		}))
+
+	// 覆盖写入时旧的 tag 不再生效
+	g.Set("key01", []byte("value"), time.Time{}, "t")
+	g.Set("key01", []byte("value"), time.Time{})
+	// 被淘汰之后重新写入，不再带有 tag
+	g.Set("key02", []byte("value"), time.Time{}, "t")
+	g.Get("key03")
+	g.Get("key04")
+	g.Set("key02", []byte("value"), time.Time{})
+
+	before := g.Stats.Removes.Get()
+	g.InvalidateTag("t")
+	if n := g.Stats.Removes.Get() - before; n != 0 {
+		t.Fatalf("stale tag index removed %d keys", n)
+	}
+}
+
+func TestInvalidateTagCluster(t *testing.T) {
+	nodes := newCluster(t, 3, cache.HttpPoolOptions{})
+	groups := newClusterGroup(t, nodes, "tags", cache.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("db-" + key), nil
+		}))
+
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		if err := groups[0].Set(key, []byte("set-"+key), time.Time{}, "all", "tag-"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := groups[1].InvalidateTag("tag-a"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := groups[2].Get("b"); v.String() != "set-b" {
+		t.Fatalf("b should not be invalidated: %q", v)
+	}
+	if v, _ := groups[2].Get("a"); v.String() != "db-a" {
+		t.Fatalf("a should be reloaded: %q", v)
+	}
+
+	if err := groups[2].InvalidateTag("all"); err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range groups {
+		for _, key := range keys {
+			if v, err := g.Get(key); err != nil || v.String() != "db-"+key {
+				t.Fatalf("%s after invalidating all: %v %q", key, err, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cache"
+)
+
+// memStore 实现 Getter、Setter 和 Deleter 的数据源
+type memStore struct {
+	mtx   sync.Mutex
+	data  map[string]string
+	fails int // 接下来失败的写入次数
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string]string{"Tom": "630"}}
+}
+
+func (s *memStore) Get(key string) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if v, ok := s.data[key]; ok {
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("%s: %w", key, cache.ErrNotFound)
+}
+
+func (s *memStore) Set(key string, value []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("store unavailable")
+	}
+	s.data[key] = string(value)
+	return nil
+}
+
+func (s *memStore) Delete(key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func (s *memStore) value(key string) (string, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func TestWriteThrough(t *testing.T) {
+	store := newMemStore()
+	g := cache.ReplaceGroup("write-through", 2<<10, store)
+
+	if err := g.Set("Tom", []byte("700"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := store.value("Tom"); v != "700" {
+		t.Fatalf("store not updated: %q", v)
+	}
+
+	// 数据源写入失败时缓存保持不变
+	store.fails = 1
+	if err := g.Set("Tom", []byte("800"), time.Time{}); err == nil {
+		t.Fatal("expect store error")
+	}
+	if v, _ := g.Get("Tom"); v.String() != "700" {
+		t.Fatalf("cache changed after failed write: %q", v)
+	}
+
+	if _, err := g.Remove("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.value("Tom"); ok {
+		t.Fatal("store not deleted")
+	}
+	if g.Stats.StoreWrites.Get() != 2 || g.Stats.StoreWriteErrs.Get() != 1 {
+		t.Fatalf("unexpected stats: writes=%d errs=%d", g.Stats.StoreWrites.Get(), g.Stats.StoreWriteErrs.Get())
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	store := newMemStore()
+	store.data["Sam"] = "567"
+	g := cache.ReplaceGroup("write-behind", 2<<10, store)
+	g.SetWriteBehind(&cache.WriteBehindOptions{FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+
+	for _, v := range []string{"1", "2", "3"} {
+		if err := g.Set("Tom", []byte(v), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.Set("Jack", []byte("589"), time.Time{})
+	g.Remove("Sam")
+	if v, _ := store.value("Tom"); v != "630" {
+		t.Fatalf("store written before flush: %q", v)
+	}
+	// 缓存中已经是新的值，被删除的 key 不会从数据源读到旧值
+	if v, _ := g.Get("Tom"); v.String() != "3" {
+		t.Fatalf("cache not updated: %q", v)
+	}
+	if _, err := g.Get("Sam"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expect pending delete to hide Sam, got %v", err)
+	}
+
+	// Tom 和 Jack 第一次写入失败，在同一轮重试中完成
+	store.fails = 2
+	g.Flush()
+	for key, expect := range map[string]string{"Tom": "3", "Jack": "589"} {
+		if v, _ := store.value(key); v != expect {
+			t.Fatalf("%s: expect %q in store, got %q", key, expect, v)
+		}
+	}
+	if _, ok := store.value("Sam"); ok {
+		t.Fatal("Sam not deleted from store")
+	}
+
+	s := &g.Stats
+	if s.WritesQueued.Get() != 5 || s.WritesCoalesced.Get() != 2 || s.StoreWrites.Get() != 3 || s.WriteRetries.Get() != 1 {
+		t.Fatalf("unexpected stats: queued=%d coalesced=%d writes=%d retries=%d",
+			s.WritesQueued.Get(), s.WritesCoalesced.Get(), s.StoreWrites.Get(), s.WriteRetries.Get())
+	}
+}
+
+func TestWriteBehindBatch(t *testing.T) {
+	store := newMemStore()
+	g := cache.ReplaceGroup("write-batch", 2<<10, store)
+	g.SetWriteBehind(&cache.WriteBehindOptions{BatchSize: 2, FlushInterval: time.Hour})
+	defer g.SetWriteBehind(nil)
+
+	// 队列达到 BatchSize 时不需要等待 FlushInterval
+	g.Set("a", []byte("1"), time.Time{})
+	g.Set("b", []byte("2"), time.Time{})
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := store.value("b"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("batch not flushed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 切换回 write-through 时提交剩余的写入
+	g.Set("c", []byte("3"), time.Time{})
+	g.SetWriteBehind(nil)
+	if v, _ := store.value("c"); v != "3" {
+		t.Fatalf("pending write lost: %q", v)
+	}
+}
+
+func TestWriteBehindFallbackOrder(t *testing.T) {
+	store := newGateStore("old")
+	g := cache.ReplaceGroup("write-fallback", 2<<10, store)
+	g.SetWriteBehind(&cache.WriteBehindOptions{BatchSize: 1, MaxPending: 1, FlushInterval: time.Hour})
+	defer g.SetWriteBehind(nil)
+
+	g.Set("a", []byte("old"), time.Time{})
+	<-store.entered
+	// 队列已满时同步写入，但是要等 a 正在进行的写入完成，不会被旧值覆盖
+	g.Set("b", []byte("x"), time.Time{})
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Set("a", []byte("new"), time.Time{})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	store.release <- nil
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	g.Flush()
+	if v, _ := store.Get("a"); string(v) != "new" {
+		t.Fatalf("fallback write overwritten by queued write: %q", v)
+	}
+}
+
+func TestWriteBehindDropped(t *testing.T) {
+	store := newMemStore()
+	g := cache.ReplaceGroup("write-dropped", 2<<10, store)
+	g.SetWriteBehind(&cache.WriteBehindOptions{FlushInterval: time.Hour, MaxRetries: -1})
+	defer g.SetWriteBehind(nil)
+
+	// 写入被丢弃之后缓存中不再保留数据源中没有的值
+	g.Set("Tom", []byte("700"), time.Time{})
+	store.fails = 1
+	g.Flush()
+	if v, err := g.Get("Tom"); err != nil || v.String() != "630" {
+		t.Fatalf("expect value reloaded from store after dropped write: %v %q", err, v)
+	}
+	if g.Stats.StoreWriteErrs.Get() != 1 {
+		t.Fatalf("expect 1 dropped write, got %d", g.Stats.StoreWriteErrs.Get())
+	}
+}
@@ -0,0 +1,366 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Setter 可选接口，Getter 同时实现它时 Group.Set 会同步写入数据源
+type Setter interface {
+	Set(key string, value []byte) error
+}
+
+// SetterFunc 通过函数实现 Setter
+type SetterFunc func(key string, value []byte) error
+
+func (f SetterFunc) Set(key string, value []byte) error {
+	return f(key, value)
+}
+
+// Deleter 可选接口，Getter 同时实现它时 Group.Remove 会同步删除数据源中的 key
+type Deleter interface {
+	Delete(key string) error
+}
+
+// DeleterFunc 通过函数实现 Deleter
+type DeleterFunc func(key string) error
+
+func (f DeleterFunc) Delete(key string) error {
+	return f(key)
+}
+
+// Write 一次写入或者删除
+type Write struct {
+	Key string
+	Entry
+	Delete bool
+}
+
+// BatchWriter 可选接口，write-behind 模式下一批写入通过它一次提交，
+// 返回错误时整批都会重试
+type BatchWriter interface {
+	WriteBatch(writes []Write) error
+}
+
+const (
+	defaultWriteBatchSize     = 100
+	defaultWriteFlushInterval = time.Second
+	defaultWriteMaxPending    = 10000
+	defaultWriteMaxRetries    = 3
+	defaultWriteRetryBackoff  = 100 * time.Millisecond
+)
+
+// WriteBehindOptions write-behind 模式的配置
+// 写入先更新缓存，之后在后台按批写入数据源，同一个 key 在写入之前的多次修改只保留最后一次
+type WriteBehindOptions struct {
+	BatchSize     int           // 每批最多写入的 key 数量，队列达到这个数量时立即写入，默认 100
+	FlushInterval time.Duration // 定时写入的间隔，默认 1s
+	MaxPending    int           // 队列中最多的 key 数量，超出时等待同一个 key 正在进行的写入完成之后同步写入，默认 10000
+	MaxRetries    int           // 失败后的重试次数，默认 3，小于 0 时不重试；仍然失败的写入被丢弃，同时删除对应的缓存
+	RetryBackoff  time.Duration // 第一次重试之前等待的时间，之后每次翻倍，默认 100ms
+}
+
+// SetWriteBehind 开启 write-behind 模式，opts 为 nil 时恢复为 write-through
+// 切换时会先把队列中的写入全部提交
+func (g *Group) SetWriteBehind(opts *WriteBehindOptions) {
+	var wb *writeBehind
+	if opts != nil {
+		wb = newWriteBehind(g, *opts)
+	}
+	g.writeMtx.Lock()
+	old := g.writeBehind
+	g.writeBehind = wb
+	g.writeMtx.Unlock()
+	if old != nil {
+		old.close()
+	}
+}
+
+// Flush 等待 write-behind 队列中的写入全部提交，write-through 模式下直接返回
+func (g *Group) Flush() {
+	if wb := g.writer(); wb != nil {
+		wb.flush()
+	}
+}
+
+func (g *Group) writer() *writeBehind {
+	g.writeMtx.Lock()
+	defer g.writeMtx.Unlock()
+	return g.writeBehind
+}
+
+// writeStore 将写入同步到数据源，Getter 没有实现 Setter/Deleter 时什么也不做
+func (g *Group) writeStore(w Write) error {
+	if !g.hasStore(w) {
+		return nil
+	}
+	if wb := g.writer(); wb != nil && wb.enqueue(w) {
+		return nil
+	}
+	if err := g.write(w); err != nil {
+		g.Stats.StoreWriteErrs.Add(1)
+		return fmt.Errorf("write %s to store: %w", w.Key, err)
+	}
+	g.Stats.StoreWrites.Add(1)
+	return nil
+}
+
+func (g *Group) hasStore(w Write) bool {
+	if w.Delete {
+		_, ok := g.getter.(Deleter)
+		return ok
+	}
+	_, ok := g.getter.(Setter)
+	return ok
+}
+
+func (g *Group) write(w Write) error {
+	if w.Delete {
+		return g.getter.(Deleter).Delete(w.Key)
+	}
+	return g.getter.(Setter).Set(w.Key, w.Value)
+}
+
+// pendingWrite 返回还没有写入数据源的修改，加载时优先使用它，避免读到旧的数据
+func (g *Group) pendingWrite(key string) (Write, bool) {
+	if wb := g.writer(); wb != nil {
+		return wb.get(key)
+	}
+	return Write{}, false
+}
+
+type writeBehind struct {
+	g    *Group
+	opts WriteBehindOptions
+
+	mtx      sync.Mutex
+	pending  map[string]Write // 等待写入的修改，每个 key 只保留最后一次
+	order    []string         // pending 中的 key，按照第一次写入的顺序
+	flushing map[string]Write // 正在写入的一批
+	closed   bool
+	settled  *sync.Cond // flushing 中的写入完成时通知
+
+	kick    chan struct{}
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newWriteBehind(g *Group, opts WriteBehindOptions) *writeBehind {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultWriteBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultWriteFlushInterval
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = defaultWriteMaxPending
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultWriteMaxRetries
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultWriteRetryBackoff
+	}
+	wb := &writeBehind{
+		g:        g,
+		opts:     opts,
+		pending:  make(map[string]Write),
+		flushing: make(map[string]Write),
+		kick:     make(chan struct{}, 1),
+		flushes:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	wb.settled = sync.NewCond(&wb.mtx)
+	go wb.run()
+	return wb
+}
+
+// enqueue 加入队列，队列已满或者已经关闭时返回 false，由调用方同步写入。
+// 返回 false 之前等待同一个 key 还没有完成的写入，同步写入不会被之前的写入覆盖
+func (wb *writeBehind) enqueue(w Write) bool {
+	wb.mtx.Lock()
+	if wb.closed {
+		wb.waitKey(w.Key)
+		wb.mtx.Unlock()
+		return false
+	}
+	if _, ok := wb.pending[w.Key]; ok {
+		wb.g.Stats.WritesCoalesced.Add(1)
+	} else {
+		if len(wb.order) >= wb.opts.MaxPending {
+			wb.waitKey(w.Key)
+			wb.mtx.Unlock()
+			return false
+		}
+		wb.order = append(wb.order, w.Key)
+	}
+	w.Value = cloneBytes(w.Value)
+	w.Tags = cloneTags(w.Tags)
+	wb.pending[w.Key] = w
+	full := len(wb.order) >= wb.opts.BatchSize
+	wb.mtx.Unlock()
+
+	wb.g.Stats.WritesQueued.Add(1)
+	if full {
+		select {
+		case wb.kick <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// waitKey 等待 key 在队列中以及正在进行的写入完成，调用方需要持有锁
+func (wb *writeBehind) waitKey(key string) {
+	for {
+		_, queued := wb.pending[key]
+		_, flushing := wb.flushing[key]
+		if !queued && !flushing {
+			return
+		}
+		wb.settled.Wait()
+	}
+}
+
+func (wb *writeBehind) get(key string) (Write, bool) {
+	wb.mtx.Lock()
+	defer wb.mtx.Unlock()
+	if w, ok := wb.pending[key]; ok {
+		return w, true
+	}
+	w, ok := wb.flushing[key]
+	return w, ok
+}
+
+// flush 等待当前队列中的写入全部提交
+func (wb *writeBehind) flush() {
+	done := make(chan struct{})
+	select {
+	case wb.flushes <- done:
+		<-done
+	case <-wb.done:
+	}
+}
+
+// close 提交剩余的写入并停止后台的 goroutine，之后的写入由调用方同步完成
+func (wb *writeBehind) close() {
+	wb.mtx.Lock()
+	wb.closed = true
+	wb.mtx.Unlock()
+	close(wb.stop)
+	<-wb.done
+}
+
+func (wb *writeBehind) run() {
+	defer close(wb.done)
+	ticker := time.NewTicker(wb.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			wb.drain()
+		case <-wb.kick:
+			wb.drain()
+		case done := <-wb.flushes:
+			wb.drain()
+			close(done)
+		case <-wb.stop:
+			wb.drain()
+			return
+		}
+	}
+}
+
+// drain 按批写入队列中所有的修改
+func (wb *writeBehind) drain() {
+	for {
+		batch := wb.take()
+		if len(batch) == 0 {
+			return
+		}
+		wb.commit(batch)
+
+		wb.mtx.Lock()
+		for _, w := range batch {
+			delete(wb.flushing, w.Key)
+		}
+		wb.settled.Broadcast()
+		wb.mtx.Unlock()
+	}
+}
+
+// take 从队列中取出一批
+func (wb *writeBehind) take() []Write {
+	wb.mtx.Lock()
+	defer wb.mtx.Unlock()
+	n := len(wb.order)
+	if n > wb.opts.BatchSize {
+		n = wb.opts.BatchSize
+	}
+	batch := make([]Write, 0, n)
+	for _, key := range wb.order[:n] {
+		w := wb.pending[key]
+		delete(wb.pending, key)
+		wb.flushing[key] = w
+		batch = append(batch, w)
+	}
+	wb.order = wb.order[n:]
+	return batch
+}
+
+// commit 写入一批，失败的部分按照 RetryBackoff 重试
+func (wb *writeBehind) commit(batch []Write) {
+	backoff := wb.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		failed, err := wb.writeBatch(batch)
+		wb.g.Stats.StoreWrites.Add(int64(len(batch) - len(failed)))
+		if len(failed) == 0 {
+			return
+		}
+		if attempt >= wb.opts.MaxRetries {
+			wb.g.Stats.StoreWriteErrs.Add(int64(len(failed)))
+			log.Printf("[GeeCache] group %s: dropped %d writes after %d retries: %v", wb.g.name, len(failed), attempt, err)
+			wb.discard(failed)
+			return
+		}
+		wb.g.Stats.WriteRetries.Add(1)
+		time.Sleep(backoff)
+		backoff *= 2
+		batch = failed
+	}
+}
+
+// discard 删除被丢弃的写入对应的缓存，之后从数据源重新加载，不会一直返回数据源中没有的值。
+// 之后又修改过的 key 保留，新的写入仍然在队列中
+func (wb *writeBehind) discard(failed []Write) {
+	wb.mtx.Lock()
+	defer wb.mtx.Unlock()
+	for _, w := range failed {
+		if _, ok := wb.pending[w.Key]; !ok {
+			wb.g.mainCache.remove(w.Key)
+		}
+	}
+}
+
+// writeBatch 返回写入失败的部分以及最后一个错误
+func (wb *writeBehind) writeBatch(batch []Write) ([]Write, error) {
+	if bw, ok := wb.g.getter.(BatchWriter); ok {
+		if err := bw.WriteBatch(batch); err != nil {
+			return batch, err
+		}
+		return nil, nil
+	}
+	var failed []Write
+	var lastErr error
+	for _, w := range batch {
+		if err := wb.g.write(w); err != nil {
+			failed = append(failed, w)
+			lastErr = err
+		}
+	}
+	return failed, lastErr
+}